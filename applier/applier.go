@@ -0,0 +1,343 @@
+package applier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultFieldManager is the field manager that is used when applying objects.
+const DefaultFieldManager = "gitops-tools"
+
+const (
+	defaultWaitInterval = time.Second * 2
+	defaultWaitTimeout  = time.Minute * 5
+)
+
+// Action is the action taken for an object.
+type Action string
+
+// The actions that can be taken for an object.
+const (
+	Created    Action = "created"
+	Configured Action = "configured"
+	Unchanged  Action = "unchanged"
+	Pruned     Action = "pruned"
+	Failed     Action = "failed"
+)
+
+// ObjectRef identifies an applied object, and is recorded in the inventory so
+// that the object can be pruned when it is no longer in the manifests.
+type ObjectRef struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+}
+
+// String returns the reference as Kind/namespace/name.
+func (r ObjectRef) String() string {
+	if r.Namespace == "" {
+		return r.Kind + "/" + r.Name
+	}
+	return r.Kind + "/" + r.Namespace + "/" + r.Name
+}
+
+func (r ObjectRef) groupKind() schema.GroupKind {
+	return schema.FromAPIVersionAndKind(r.APIVersion, r.Kind).GroupKind()
+}
+
+// Result is the result of applying or pruning an object.
+type Result struct {
+	Object ObjectRef
+	Action Action
+	// Err is the error from applying, pruning or waiting for the object.
+	Err error
+}
+
+// Report is the result of applying a set of manifests.
+type Report struct {
+	// Results has a result for each object that was applied, followed by a
+	// result for each object that was pruned.
+	Results []Result
+	// Inventory is the set of objects in the applied manifests, which should
+	// be provided as the Inventory the next time the manifests are applied.
+	Inventory []ObjectRef
+}
+
+// ApplyOptions configures the application of manifests.
+type ApplyOptions struct {
+	// Namespace is set on namespaced objects that don't have a namespace.
+	Namespace string
+	// Inventory is the set of objects from the previous application, objects
+	// in the Inventory that are not in the manifests are deleted.
+	//
+	// Objects are only pruned if all the objects are applied successfully.
+	Inventory []ObjectRef
+	// Wait waits for the applied objects to be ready.
+	//
+	// Deployments are ready when they have rolled out, and Jobs when they are
+	// complete, other objects are ready as soon as they are applied.
+	Wait bool
+	// Timeout is the maximum time to wait for all the objects to be ready, the
+	// default is five minutes.
+	Timeout time.Duration
+}
+
+// Applier applies manifests to a cluster with server-side apply.
+type Applier struct {
+	kubeClient   client.Client
+	fieldManager string
+	waitInterval time.Duration
+}
+
+// Option is an option func for the Applier creation function.
+type Option func(a *Applier)
+
+// FieldManager is an option func for the Applier creation function that
+// replaces the DefaultFieldManager used when applying objects.
+func FieldManager(name string) Option {
+	return func(a *Applier) {
+		a.fieldManager = name
+	}
+}
+
+// WaitInterval is an option func for the Applier creation function that sets
+// how often objects are checked when waiting for them to be ready, the
+// default is two seconds.
+func WaitInterval(d time.Duration) Option {
+	return func(a *Applier) {
+		a.waitInterval = d
+	}
+}
+
+// New creates and returns an Applier that applies objects with the client,
+// e.g. a client from kubeconfig.ClientFromSecret.
+func New(c client.Client, opts ...Option) *Applier {
+	a := &Applier{
+		kubeClient:   c,
+		fieldManager: DefaultFieldManager,
+		waitInterval: defaultWaitInterval,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// ApplyManifests decodes multi-document YAML manifests and applies the
+// objects.
+func (a *Applier) ApplyManifests(ctx context.Context, b []byte, opts ApplyOptions) (*Report, error) {
+	objects, err := Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	return a.Apply(ctx, objects, opts)
+}
+
+// Apply applies the objects with server-side apply, taking ownership of any
+// conflicting fields.
+//
+// Namespaces and CustomResourceDefinitions are applied before other objects.
+//
+// An error is returned if any of the objects fail, and the Report has the
+// result for each object.
+//
+// The objects are not modified.
+func (a *Applier) Apply(ctx context.Context, objects []*unstructured.Unstructured, opts ApplyOptions) (*Report, error) {
+	objects = sortForApply(deepCopyObjects(objects))
+	report := &Report{}
+	var errs []error
+	for _, obj := range objects {
+		result := a.apply(ctx, obj, opts)
+		report.Results = append(report.Results, result)
+		report.Inventory = append(report.Inventory, result.Object)
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("failed to apply %s: %w", result.Object, result.Err))
+		}
+	}
+
+	if opts.Wait && len(errs) == 0 {
+		errs = append(errs, a.waitForReady(ctx, objects, report, opts.Timeout)...)
+	}
+
+	if len(errs) == 0 {
+		for _, ref := range pruneRefs(opts.Inventory, report.Inventory) {
+			result := a.prune(ctx, ref)
+			report.Results = append(report.Results, result)
+			if result.Err != nil {
+				errs = append(errs, fmt.Errorf("failed to prune %s: %w", result.Object, result.Err))
+			}
+		}
+	}
+
+	return report, errors.Join(errs...)
+}
+
+func (a *Applier) apply(ctx context.Context, obj *unstructured.Unstructured, opts ApplyOptions) Result {
+	if obj.GetNamespace() == "" && opts.Namespace != "" {
+		namespaced, err := a.kubeClient.IsObjectNamespaced(obj)
+		if err != nil {
+			return Result{Object: ObjectRefFromObject(obj), Action: Failed, Err: err}
+		}
+		if namespaced {
+			obj.SetNamespace(opts.Namespace)
+		}
+	}
+	ref := ObjectRefFromObject(obj)
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	action := Configured
+	if err := a.kubeClient.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return Result{Object: ref, Action: Failed, Err: err}
+		}
+		action = Created
+	}
+
+	applied := obj.DeepCopy()
+	if err := a.kubeClient.Apply(ctx, client.ApplyConfigurationFromUnstructured(applied), client.FieldOwner(a.fieldManager), client.ForceOwnership); err != nil {
+		return Result{Object: ref, Action: Failed, Err: err}
+	}
+	if action == Configured && applied.GetResourceVersion() == existing.GetResourceVersion() {
+		action = Unchanged
+	}
+
+	return Result{Object: ref, Action: action}
+}
+
+func (a *Applier) prune(ctx context.Context, ref ObjectRef) Result {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(ref.APIVersion)
+	obj.SetKind(ref.Kind)
+	obj.SetNamespace(ref.Namespace)
+	obj.SetName(ref.Name)
+	if err := a.kubeClient.Delete(ctx, obj, client.PropagationPolicy("Background")); client.IgnoreNotFound(err) != nil {
+		return Result{Object: ref, Action: Failed, Err: err}
+	}
+	return Result{Object: ref, Action: Pruned}
+}
+
+// waitForReady records an error in the result for each object that is not
+// ready before the timeout.
+func (a *Applier) waitForReady(ctx context.Context, objects []*unstructured.Unstructured, report *Report, timeout time.Duration) []error {
+	if timeout == 0 {
+		timeout = defaultWaitTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var errs []error
+	for i, obj := range objects {
+		check := readinessCheck(obj.GroupVersionKind().GroupKind())
+		if check == nil {
+			continue
+		}
+		var lastErr error
+		err := wait.PollUntilContextCancel(ctx, a.waitInterval, true, func(ctx context.Context) (bool, error) {
+			current := &unstructured.Unstructured{}
+			current.SetGroupVersionKind(obj.GroupVersionKind())
+			if err := a.kubeClient.Get(ctx, client.ObjectKeyFromObject(obj), current); err != nil {
+				if isTerminalError(err) {
+					return false, err
+				}
+				lastErr = err
+				return false, nil
+			}
+			lastErr = nil
+			return check(current)
+		})
+		if err != nil {
+			switch {
+			case wait.Interrupted(err) && lastErr != nil:
+				err = fmt.Errorf("timed out waiting to be ready, last error %v: %w", lastErr, err)
+			case wait.Interrupted(err):
+				err = fmt.Errorf("timed out waiting to be ready: %w", err)
+			}
+			report.Results[i].Err = err
+			errs = append(errs, fmt.Errorf("failed waiting for %s: %w", report.Results[i].Object, err))
+		}
+	}
+	return errs
+}
+
+// isTerminalError returns true if getting an object failed with an error that
+// won't be resolved by retrying.
+//
+// Other errors, including NotFound errors from caches that haven't observed
+// the applied object, are retried until the wait times out.
+func isTerminalError(err error) bool {
+	return apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) || apierrors.IsBadRequest(err) ||
+		apierrors.IsMethodNotSupported(err) || meta.IsNoMatchError(err)
+}
+
+// pruneRefs returns the refs in the previous inventory that are not in the
+// current inventory, in reverse order.
+//
+// Refs are compared by group and kind, so that objects are not pruned when
+// their apiVersion changes.
+func pruneRefs(previous, current []ObjectRef) []ObjectRef {
+	type key struct {
+		groupKind       schema.GroupKind
+		namespace, name string
+	}
+	applied := map[key]bool{}
+	for _, ref := range current {
+		applied[key{ref.groupKind(), ref.Namespace, ref.Name}] = true
+	}
+	var refs []ObjectRef
+	for _, ref := range slices.Backward(previous) {
+		if !applied[key{ref.groupKind(), ref.Namespace, ref.Name}] {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// sortForApply returns the objects with Namespaces and
+// CustomResourceDefinitions first, so that the objects that depend on them can
+// be applied.
+func sortForApply(objects []*unstructured.Unstructured) []*unstructured.Unstructured {
+	priority := func(obj *unstructured.Unstructured) int {
+		switch obj.GroupVersionKind().GroupKind() {
+		case schema.GroupKind{Kind: "Namespace"}:
+			return 0
+		case schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}:
+			return 1
+		}
+		return 2
+	}
+	sorted := slices.Clone(objects)
+	slices.SortStableFunc(sorted, func(a, b *unstructured.Unstructured) int {
+		return priority(a) - priority(b)
+	})
+	return sorted
+}
+
+func deepCopyObjects(objects []*unstructured.Unstructured) []*unstructured.Unstructured {
+	copied := make([]*unstructured.Unstructured, len(objects))
+	for i, obj := range objects {
+		copied[i] = obj.DeepCopy()
+	}
+	return copied
+}
+
+// ObjectRefFromObject returns the ObjectRef for an object.
+func ObjectRefFromObject(obj *unstructured.Unstructured) ObjectRef {
+	return ObjectRef{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
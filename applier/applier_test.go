@@ -0,0 +1,317 @@
+package applier
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gitops-tools/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+const testManifests = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test-config
+data:
+  key: value
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test-deployment
+spec:
+  selector:
+    matchLabels:
+      app: test
+  template:
+    metadata:
+      labels:
+        app: test
+    spec:
+      containers:
+      - name: test
+        image: test:latest
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: test
+`
+
+var (
+	testNamespaceRef  = ObjectRef{APIVersion: "v1", Kind: "Namespace", Name: "test"}
+	testConfigMapRef  = ObjectRef{APIVersion: "v1", Kind: "ConfigMap", Namespace: "test", Name: "test-config"}
+	testDeploymentRef = ObjectRef{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "test", Name: "test-deployment"}
+)
+
+func TestApplyManifests(t *testing.T) {
+	cl := newFakeClientBuilder().Build()
+	a := New(cl)
+
+	report, err := a.ApplyManifests(context.TODO(), []byte(testManifests), ApplyOptions{Namespace: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResults(t, []Result{
+		{Object: testNamespaceRef, Action: Created},
+		{Object: testConfigMapRef, Action: Created},
+		{Object: testDeploymentRef, Action: Created},
+	}, report.Results)
+	if diff := cmp.Diff([]ObjectRef{testNamespaceRef, testConfigMapRef, testDeploymentRef}, report.Inventory); diff != "" {
+		t.Fatalf("failed to record inventory:\n%s", diff)
+	}
+	var cm corev1.ConfigMap
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: "test", Name: "test-config"}, &cm); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"key": "value"}, cm.Data); diff != "" {
+		t.Fatalf("failed to apply ConfigMap:\n%s", diff)
+	}
+}
+
+func TestApplyManifestsUpdates(t *testing.T) {
+	cl := newFakeClientBuilder().Build()
+	a := New(cl)
+	if _, err := a.ApplyManifests(context.TODO(), []byte(testManifests), ApplyOptions{Namespace: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := Decode([]byte(testManifests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unstructured.SetNestedField(objects[0].Object, "updated", "data", "key"); err != nil {
+		t.Fatal(err)
+	}
+	report, err := a.Apply(context.TODO(), objects, ApplyOptions{Namespace: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The fake client changes the resourceVersion for applies that don't
+	// change the object, so unchanged objects are tested with envtest.
+	assertResults(t, []Result{
+		{Object: testNamespaceRef, Action: Configured},
+		{Object: testConfigMapRef, Action: Configured},
+		{Object: testDeploymentRef, Action: Configured},
+	}, report.Results)
+}
+
+func TestApplyDoesNotModifyObjects(t *testing.T) {
+	cl := newFakeClientBuilder().Build()
+	a := New(cl)
+	objects, err := Decode([]byte(testManifests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Decode([]byte(testManifests))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.Apply(context.TODO(), objects, ApplyOptions{Namespace: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(want, objects); diff != "" {
+		t.Fatalf("applying modified the objects:\n%s", diff)
+	}
+}
+
+func TestApplyPrunesInventory(t *testing.T) {
+	oldConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "old-config", Namespace: "test"}}
+	cl := newFakeClientBuilder().WithObjects(oldConfigMap).Build()
+	a := New(cl)
+	oldConfigMapRef := ObjectRef{APIVersion: "v1", Kind: "ConfigMap", Namespace: "test", Name: "old-config"}
+	deletedRef := ObjectRef{APIVersion: "v1", Kind: "Secret", Namespace: "test", Name: "deleted"}
+
+	report, err := a.ApplyManifests(context.TODO(), []byte(testManifests), ApplyOptions{
+		Namespace: "test",
+		Inventory: []ObjectRef{testNamespaceRef, oldConfigMapRef, deletedRef, testConfigMapRef},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertResults(t, []Result{
+		{Object: testNamespaceRef, Action: Created},
+		{Object: testConfigMapRef, Action: Created},
+		{Object: testDeploymentRef, Action: Created},
+		{Object: deletedRef, Action: Pruned},
+		{Object: oldConfigMapRef, Action: Pruned},
+	}, report.Results)
+	err = cl.Get(context.TODO(), client.ObjectKeyFromObject(oldConfigMap), &corev1.ConfigMap{})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("got %v, want not found", err)
+	}
+}
+
+func TestApplyFailureSkipsPrune(t *testing.T) {
+	oldConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "old-config", Namespace: "test"}}
+	cl := newFakeClientBuilder().WithObjects(oldConfigMap).WithInterceptorFuncs(interceptor.Funcs{
+		Apply: func(ctx context.Context, client client.WithWatch, obj runtime.ApplyConfiguration, opts ...client.ApplyOption) error {
+			if u, ok := obj.(interface{ GetKind() string }); ok && u.GetKind() == "Deployment" {
+				return errors.New("test failure")
+			}
+			return client.Apply(ctx, obj, opts...)
+		},
+	}).Build()
+	a := New(cl)
+
+	report, err := a.ApplyManifests(context.TODO(), []byte(testManifests), ApplyOptions{
+		Namespace: "test",
+		Inventory: []ObjectRef{{APIVersion: "v1", Kind: "ConfigMap", Namespace: "test", Name: "old-config"}},
+	})
+	if !test.MatchError(t, "failed to apply Deployment/test/test-deployment: test failure", err) {
+		t.Fatalf("got error %v", err)
+	}
+
+	assertResults(t, []Result{
+		{Object: testNamespaceRef, Action: Created},
+		{Object: testConfigMapRef, Action: Created},
+		{Object: testDeploymentRef, Action: Failed, Err: errors.New("test failure")},
+	}, report.Results)
+	if err := cl.Get(context.TODO(), client.ObjectKeyFromObject(oldConfigMap), &corev1.ConfigMap{}); err != nil {
+		t.Fatalf("failed to get unpruned ConfigMap: %s", err)
+	}
+}
+
+func TestApplyWait(t *testing.T) {
+	rolledOut := map[string]any{"replicas": int64(1), "updatedReplicas": int64(1), "availableReplicas": int64(1)}
+	deploymentResource := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	waitTests := []struct {
+		name      string
+		status    map[string]any
+		getErrs   []error
+		wantErr   string
+		wantRetry bool
+	}{
+		{name: "ready", status: rolledOut},
+		{name: "not ready", status: map[string]any{"replicas": int64(1)}, wantErr: "failed waiting for Deployment/test/test-deployment: timed out waiting to be ready", wantRetry: true},
+		{name: "transient errors", status: rolledOut, getErrs: []error{
+			apierrors.NewNotFound(deploymentResource, "test-deployment"),
+			apierrors.NewServiceUnavailable("unavailable"),
+		}},
+		{name: "persistent errors", status: rolledOut, getErrs: slices.Repeat([]error{apierrors.NewServiceUnavailable("unavailable")}, 100),
+			wantErr: "failed waiting for Deployment/test/test-deployment: timed out waiting to be ready, last error unavailable", wantRetry: true},
+		{name: "terminal error", status: rolledOut, getErrs: []error{apierrors.NewForbidden(deploymentResource, "test-deployment", errors.New("denied"))},
+			wantErr: `failed waiting for Deployment/test/test-deployment: deployments.apps "test-deployment" is forbidden: denied`},
+	}
+
+	for _, tt := range waitTests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The first Get of the Deployment is made when it is applied, the
+			// errors are returned from the Gets made when waiting for it.
+			deploymentGets := 0
+			cl := newFakeClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+				Get: func(ctx context.Context, client client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					u, ok := obj.(*unstructured.Unstructured)
+					if !ok || u.GetKind() != "Deployment" {
+						return client.Get(ctx, key, obj, opts...)
+					}
+					deploymentGets++
+					if i := deploymentGets - 2; i >= 0 && i < len(tt.getErrs) {
+						return tt.getErrs[i]
+					}
+					if err := client.Get(ctx, key, obj, opts...); err != nil {
+						return err
+					}
+					return unstructured.SetNestedMap(u.Object, tt.status, "status")
+				},
+			}).Build()
+			a := New(cl, WaitInterval(time.Millisecond*10))
+
+			report, err := a.ApplyManifests(context.TODO(), []byte(testManifests), ApplyOptions{
+				Namespace: "test",
+				Wait:      true,
+				Timeout:   time.Millisecond * 100,
+			})
+			if !test.MatchError(t, tt.wantErr, err) {
+				t.Fatalf("failed to match error %v against %s", err, tt.wantErr)
+			}
+			if tt.wantRetry && !wait.Interrupted(errors.Unwrap(report.Results[2].Err)) {
+				t.Fatalf("got result error %v, want timeout", report.Results[2].Err)
+			}
+		})
+	}
+}
+
+func TestApplierWithEnvTest(t *testing.T) {
+	testEnv := &envtest.Environment{}
+	testCfg, err := testEnv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := testEnv.Stop(); err != nil {
+			t.Fatalf("failed to shutdown testEnv: %s", err)
+		}
+	}()
+
+	cl, err := client.New(testCfg, client.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(cl)
+
+	report, err := a.ApplyManifests(context.TODO(), []byte(testManifests), ApplyOptions{Namespace: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResults(t, []Result{
+		{Object: testNamespaceRef, Action: Created},
+		{Object: testConfigMapRef, Action: Created},
+		{Object: testDeploymentRef, Action: Created},
+	}, report.Results)
+
+	objects, err := Decode([]byte(testManifests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Drop the Deployment, which is pruned from the previous inventory.
+	report, err = a.Apply(context.TODO(), objects[:1], ApplyOptions{Namespace: "test", Inventory: report.Inventory, Wait: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResults(t, []Result{
+		{Object: testConfigMapRef, Action: Unchanged},
+		{Object: testDeploymentRef, Action: Pruned},
+		{Object: testNamespaceRef, Action: Pruned},
+	}, report.Results)
+}
+
+// newFakeClientBuilder returns a fake client builder with the scopes of the
+// built-in resources, so that namespaced objects can be detected.
+func newFakeClientBuilder() *fake.ClientBuilder {
+	return fake.NewClientBuilder().WithRESTMapper(testrestmapper.TestOnlyStaticRESTMapper(clientgoscheme.Scheme))
+}
+
+func assertResults(t *testing.T, want, got []Result) {
+	t.Helper()
+	if diff := cmp.Diff(want, got, cmp.Comparer(func(x, y error) bool {
+		if x == nil || y == nil {
+			return x == y
+		}
+		return x.Error() == y.Error()
+	})); diff != "" {
+		t.Fatalf("failed to match results:\n%s", diff)
+	}
+}
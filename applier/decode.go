@@ -0,0 +1,64 @@
+package applier
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Decode parses multi-document YAML or JSON into unstructured objects.
+//
+// Empty documents are skipped, and the items in List objects are returned as
+// individual objects.
+func Decode(b []byte) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(b), 4096)
+	var objects []*unstructured.Unstructured
+	for doc := 1; ; doc++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("failed to decode document %d: %w", doc, err)
+		}
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
+		}
+		// Whole numbers are decoded as int64 rather than float64, as they are
+		// in the objects read from the cluster.
+		var content map[string]any
+		if err := utiljson.Unmarshal(raw, &content); err != nil {
+			return nil, fmt.Errorf("failed to decode document %d: %w", doc, err)
+		}
+		if len(content) == 0 {
+			continue
+		}
+
+		u := &unstructured.Unstructured{Object: content}
+		if u.GetAPIVersion() == "" || u.GetKind() == "" {
+			return nil, fmt.Errorf("object in document %d is missing apiVersion or kind", doc)
+		}
+		if !u.IsList() {
+			objects = append(objects, u)
+			continue
+		}
+		err := u.EachListItem(func(obj runtime.Object) error {
+			item, ok := obj.(*unstructured.Unstructured)
+			if !ok || item.GetAPIVersion() == "" || item.GetKind() == "" {
+				return fmt.Errorf("item in document %d is missing apiVersion or kind", doc)
+			}
+			objects = append(objects, item)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package applier
+
+import (
+	"testing"
+
+	"github.com/gitops-tools/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDecode(t *testing.T) {
+	decodeTests := []struct {
+		name     string
+		manifest string
+		want     []ObjectRef
+	}{
+		{
+			name:     "empty",
+			manifest: "",
+		},
+		{
+			name: "multiple documents",
+			manifest: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+---
+# a comment
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: second
+  namespace: test
+`,
+			want: []ObjectRef{
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "first"},
+				{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "test", Name: "second"},
+			},
+		},
+		{
+			name:     "JSON",
+			manifest: `{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"test"}}`,
+			want:     []ObjectRef{{APIVersion: "v1", Kind: "Namespace", Name: "test"}},
+		},
+		{
+			name: "list",
+			manifest: `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: first
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    name: second
+`,
+			want: []ObjectRef{
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "first"},
+				{APIVersion: "v1", Kind: "Secret", Name: "second"},
+			},
+		},
+	}
+
+	for _, tt := range decodeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := Decode([]byte(tt.manifest))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []ObjectRef
+			for _, obj := range objects {
+				got = append(got, ObjectRefFromObject(obj))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("failed to decode:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeNumbers(t *testing.T) {
+	objects, err := Decode([]byte("apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replicas, _, err := unstructured.NestedInt64(objects[0].Object, "spec", "replicas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicas != 3 {
+		t.Fatalf("got %d replicas, want 3", replicas)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	errorTests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{
+			name:     "invalid YAML",
+			manifest: "apiVersion: v1\nkind: ConfigMap\n---\nkind: [\n",
+			wantErr:  "failed to decode document 2",
+		},
+		{
+			name:     "missing kind",
+			manifest: "apiVersion: v1\nmetadata:\n  name: test\n",
+			wantErr:  "object in document 1 is missing apiVersion or kind",
+		},
+		{
+			name:     "list item missing kind",
+			manifest: "apiVersion: v1\nkind: List\nitems:\n- apiVersion: v1\n  metadata:\n    name: test\n",
+			wantErr:  "item in document 1 is missing apiVersion or kind",
+		},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.manifest))
+			if !test.MatchError(t, tt.wantErr, err) {
+				t.Fatalf("failed to match error %s against %s", err, tt.wantErr)
+			}
+		})
+	}
+}
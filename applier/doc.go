@@ -0,0 +1,4 @@
+// Package applier provides functionality for applying multi-document YAML
+// manifests to a cluster with server-side apply, pruning objects that were
+// previously applied, and waiting for the applied objects to be ready.
+package applier
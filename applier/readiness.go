@@ -0,0 +1,78 @@
+package applier
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// readyFunc returns true when the object is ready, and an error if the object
+// will never be ready.
+type readyFunc func(obj *unstructured.Unstructured) (bool, error)
+
+// readinessCheck returns the check for the kind, or nil if objects of the
+// kind are ready as soon as they are applied.
+func readinessCheck(gk schema.GroupKind) readyFunc {
+	switch gk {
+	case schema.GroupKind{Group: "apps", Kind: "Deployment"}:
+		return deploymentReady
+	case schema.GroupKind{Group: "batch", Kind: "Job"}:
+		return jobReady
+	}
+	return nil
+}
+
+// deploymentReady returns true when the latest generation of the Deployment
+// has been observed, and all the replicas are updated and available, and an
+// error if the rollout exceeded its progress deadline.
+func deploymentReady(obj *unstructured.Unstructured) (bool, error) {
+	observedGeneration, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if observedGeneration < obj.GetGeneration() {
+		return false, nil
+	}
+	if condition := findCondition(obj, "Progressing"); condition != nil && condition["reason"] == "ProgressDeadlineExceeded" {
+		return false, fmt.Errorf("deployment exceeded its progress deadline: %v", condition["message"])
+	}
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+	for _, field := range []string{"replicas", "updatedReplicas", "availableReplicas"} {
+		v, _, _ := unstructured.NestedInt64(obj.Object, "status", field)
+		if v != replicas {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// jobReady returns true when the Job is complete, and an error if it failed.
+func jobReady(obj *unstructured.Unstructured) (bool, error) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]any)
+		if !ok || condition["status"] != "True" {
+			continue
+		}
+		switch condition["type"] {
+		case "Complete":
+			return true, nil
+		case "Failed":
+			return false, fmt.Errorf("job failed: %v", condition["message"])
+		}
+	}
+	return false, nil
+}
+
+// findCondition returns the status condition of the type, or nil if the
+// object doesn't have it.
+func findCondition(obj *unstructured.Unstructured, conditionType string) map[string]any {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		if condition, ok := c.(map[string]any); ok && condition["type"] == conditionType {
+			return condition
+		}
+	}
+	return nil
+}
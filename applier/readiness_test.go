@@ -0,0 +1,120 @@
+package applier
+
+import (
+	"testing"
+
+	"github.com/gitops-tools/pkg/test"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestReadinessChecks(t *testing.T) {
+	readyTests := []struct {
+		name    string
+		obj     map[string]any
+		want    bool
+		wantErr string
+	}{
+		{
+			name: "deployment rolled out",
+			obj: map[string]any{
+				"apiVersion": "apps/v1", "kind": "Deployment",
+				"metadata": map[string]any{"generation": int64(2)},
+				"spec":     map[string]any{"replicas": int64(2)},
+				"status":   map[string]any{"observedGeneration": int64(2), "replicas": int64(2), "updatedReplicas": int64(2), "availableReplicas": int64(2)},
+			},
+			want: true,
+		},
+		{
+			name: "deployment with default replicas",
+			obj: map[string]any{
+				"apiVersion": "apps/v1", "kind": "Deployment",
+				"status": map[string]any{"replicas": int64(1), "updatedReplicas": int64(1), "availableReplicas": int64(1)},
+			},
+			want: true,
+		},
+		{
+			name: "deployment generation not observed",
+			obj: map[string]any{
+				"apiVersion": "apps/v1", "kind": "Deployment",
+				"metadata": map[string]any{"generation": int64(3)},
+				"status":   map[string]any{"observedGeneration": int64(2), "replicas": int64(1), "updatedReplicas": int64(1), "availableReplicas": int64(1)},
+			},
+		},
+		{
+			name: "deployment rolling out",
+			obj: map[string]any{
+				"apiVersion": "apps/v1", "kind": "Deployment",
+				"spec":   map[string]any{"replicas": int64(3)},
+				"status": map[string]any{"replicas": int64(3), "updatedReplicas": int64(1), "availableReplicas": int64(3)},
+			},
+		},
+		{
+			name: "deployment exceeded progress deadline",
+			obj: map[string]any{
+				"apiVersion": "apps/v1", "kind": "Deployment",
+				"spec": map[string]any{"replicas": int64(3)},
+				"status": map[string]any{
+					"replicas": int64(3), "updatedReplicas": int64(1), "availableReplicas": int64(2),
+					"conditions": []any{map[string]any{"type": "Progressing", "status": "False", "reason": "ProgressDeadlineExceeded", "message": `ReplicaSet "test-5d8f" has timed out progressing.`}},
+				},
+			},
+			wantErr: `deployment exceeded its progress deadline: ReplicaSet "test-5d8f" has timed out progressing.`,
+		},
+		{
+			name: "deployment progressing",
+			obj: map[string]any{
+				"apiVersion": "apps/v1", "kind": "Deployment",
+				"spec": map[string]any{"replicas": int64(3)},
+				"status": map[string]any{
+					"replicas": int64(3), "updatedReplicas": int64(1), "availableReplicas": int64(2),
+					"conditions": []any{map[string]any{"type": "Progressing", "status": "True", "reason": "ReplicaSetUpdated"}},
+				},
+			},
+		},
+		{
+			name: "job complete",
+			obj: map[string]any{
+				"apiVersion": "batch/v1", "kind": "Job",
+				"status": map[string]any{"conditions": []any{map[string]any{"type": "Complete", "status": "True"}}},
+			},
+			want: true,
+		},
+		{
+			name: "job running",
+			obj: map[string]any{
+				"apiVersion": "batch/v1", "kind": "Job",
+				"status": map[string]any{"active": int64(1)},
+			},
+		},
+		{
+			name: "job failed",
+			obj: map[string]any{
+				"apiVersion": "batch/v1", "kind": "Job",
+				"status": map[string]any{"conditions": []any{map[string]any{"type": "Failed", "status": "True", "message": "BackoffLimitExceeded"}}},
+			},
+			wantErr: "job failed: BackoffLimitExceeded",
+		},
+	}
+
+	for _, tt := range readyTests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: tt.obj}
+			check := readinessCheck(obj.GroupVersionKind().GroupKind())
+
+			ready, err := check(obj)
+			if !test.MatchError(t, tt.wantErr, err) {
+				t.Fatalf("failed to match error %s against %s", err, tt.wantErr)
+			}
+			if ready != tt.want {
+				t.Fatalf("got ready %v, want %v", ready, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadinessCheckForOtherKinds(t *testing.T) {
+	if check := readinessCheck(schema.GroupKind{Kind: "ConfigMap"}); check != nil {
+		t.Fatal("ConfigMaps should be ready when applied")
+	}
+}
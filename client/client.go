@@ -7,6 +7,8 @@ import (
 	"github.com/jenkins-x/go-scm/scm"
 )
 
+const pullRequestPageSize = 100
+
 // New creates and returns a new SCMClient.
 func New(c *scm.Client) *SCMClient {
 	return &SCMClient{scmClient: c}
@@ -50,6 +52,55 @@ func (c *SCMClient) CreatePullRequest(ctx context.Context, repo string, inp *scm
 	return pr, err
 }
 
+// FindPullRequest returns the open PullRequest from the head branch to the
+// base branch.
+//
+// If there is no open PullRequest, scm.ErrNotFound is returned.
+func (c *SCMClient) FindPullRequest(ctx context.Context, repo, head, base string) (*scm.PullRequest, error) {
+	opts := &scm.PullRequestListOptions{Open: true, Page: 1, Size: pullRequestPageSize}
+	for {
+		prs, r, err := c.scmClient.PullRequests.List(ctx, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range prs {
+			if pr.Source == head && pr.Target == base && !pr.Closed {
+				return pr, nil
+			}
+		}
+		if r == nil || r.Page.Next == 0 || len(prs) == 0 {
+			return nil, scm.ErrNotFound
+		}
+		opts.Page = r.Page.Next
+	}
+}
+
+// UpdatePullRequest updates the title and body of an existing PullRequest.
+func (c *SCMClient) UpdatePullRequest(ctx context.Context, repo string, number int, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	pr, _, err := c.scmClient.PullRequests.Update(ctx, repo, number, inp)
+	return pr, err
+}
+
+// CreateFile creates a new file in a repository.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreateFile(ctx context.Context, repo, branch, path, message string, content []byte) error {
+	params := scm.ContentParams{
+		Message: message,
+		Data:    content,
+		Branch:  branch,
+	}
+	r, err := c.scmClient.Contents.Create(ctx, repo, path, &params)
+	if err != nil {
+		return err
+	}
+	if isErrorStatus(r.Status) {
+		return scmError{msg: fmt.Sprintf("failed to create file %s in repo %s branch %s", path, repo, branch), Status: r.Status}
+	}
+	return nil
+}
+
 // UpdateFile updates an existing file in a repository.
 //
 // If an HTTP error is returned by the upstream service, an error with the
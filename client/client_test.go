@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"testing"
@@ -15,7 +17,11 @@ import (
 	"gopkg.in/h2non/gock.v1"
 )
 
-var _ GitClient = (*SCMClient)(nil)
+var (
+	_ GitClient          = (*SCMClient)(nil)
+	_ FileCreator        = (*SCMClient)(nil)
+	_ PullRequestUpdater = (*SCMClient)(nil)
+)
 
 func TestGetFile(t *testing.T) {
 	gock.New("https://api.github.com").
@@ -126,6 +132,52 @@ func TestUpdateFileWithNoConnection(t *testing.T) {
 	}
 }
 
+func TestCreateFileInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/repository/commits").
+		MatchType("json").
+		Reply(http.StatusCreated).
+		Type("application/json").
+		BodyString("{}")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.CreateFile(context.TODO(), "Codertocat/Hello-World", "my-test-branch",
+		"config/my/file.yaml", "just a test message", []byte(`testing`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("file was not created")
+	}
+}
+
+func TestCreateFileWithErrorResponse(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/repository/commits").
+		Reply(http.StatusBadRequest).
+		Type("application/json").
+		BodyString(`{"message": "A file with this name already exists"}`)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.CreateFile(context.TODO(), "Codertocat/Hello-World", "my-test-branch",
+		"config/my/file.yaml", "just a test message", []byte(`testing`))
+	if err == nil {
+		t.Fatal("expected an error creating the file")
+	}
+}
+
 func TestCreateBranchInGitHub(t *testing.T) {
 	sha := "aa218f56b14c9653891f9e74264a383fa43fefbd"
 
@@ -217,6 +269,74 @@ func TestCreatePullRequest(t *testing.T) {
 	}
 }
 
+func TestFindPullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/pr_list.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	pr, err := client.FindPullRequest(context.Background(), "Codertocat/Hello-World", "new-topic", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Number != 1347 {
+		t.Fatalf("got PullRequest %d, want 1347", pr.Number)
+	}
+}
+
+func TestFindPullRequestWithNoMatchingPullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/pr_list.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.FindPullRequest(context.Background(), "Codertocat/Hello-World", "other-topic", "master")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestUpdatePullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/pulls/1347").
+		MatchType("json").
+		JSON(map[string]string{"title": "Updated feature", "body": "Updated changes"}).
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/pr_create.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.UpdatePullRequest(context.Background(), "Codertocat/Hello-World", 1347, &scm.PullRequestInput{Title: "Updated feature", Body: "Updated changes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("pull request was not updated")
+	}
+}
+
 func TestGetBranchHead(t *testing.T) {
 	gock.New("https://api.github.com").
 		Get("/repos/Codertocat/Hello-World/git/refs/heads/master").
@@ -261,3 +381,23 @@ func mustParseJSONAsContent(t *testing.T, filename string) *scm.Content {
 		Data: content,
 	}
 }
+
+func TestIsNotFound(t *testing.T) {
+	notFoundTests := []struct {
+		err  error
+		want bool
+	}{
+		{scm.ErrNotFound, true},
+		{fmt.Errorf("wrapped: %w", scm.ErrNotFound), true},
+		{scmError{msg: "testing", Status: http.StatusNotFound}, true},
+		{scmError{msg: "testing", Status: http.StatusInternalServerError}, false},
+		{errors.New("not found"), false},
+		{nil, false},
+	}
+
+	for _, tt := range notFoundTests {
+		if got := IsNotFound(tt.err); got != tt.want {
+			t.Errorf("IsNotFound(%v) got %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
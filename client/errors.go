@@ -1,13 +1,19 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+
+	"github.com/jenkins-x/go-scm/scm"
 )
 
 // IsNotFound returns true if the error represents a NotFound response from an
 // upstream service.
 func IsNotFound(err error) bool {
+	if errors.Is(err, scm.ErrNotFound) {
+		return true
+	}
 	e, ok := err.(scmError)
 	return ok && e.Status == http.StatusNotFound
 }
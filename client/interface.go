@@ -14,3 +14,20 @@ type GitClient interface {
 	CreateBranch(ctx context.Context, repo, branch, sha string) error
 	GetBranchHead(ctx context.Context, repo, branch string) (string, error)
 }
+
+// FileCreator is implemented by GitClients that can create new files in a
+// repository.
+//
+// Not all upstream services can create files with UpdateFile.
+type FileCreator interface {
+	CreateFile(ctx context.Context, repo, branch, path, message string, content []byte) error
+}
+
+// PullRequestUpdater is implemented by GitClients that can find and update
+// existing PullRequests.
+type PullRequestUpdater interface {
+	// FindPullRequest returns the open PullRequest from the head branch to the
+	// base branch, or an error that IsNotFound if there isn't one.
+	FindPullRequest(ctx context.Context, repo, head, base string) (*scm.PullRequest, error)
+	UpdatePullRequest(ctx context.Context, repo string, number int, inp *scm.PullRequestInput) (*scm.PullRequest, error)
+}
@@ -3,7 +3,6 @@ package mock
 import (
 	"context"
 	"crypto/sha1"
-	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -13,7 +12,11 @@ import (
 	"github.com/jenkins-x/go-scm/scm"
 )
 
-var _ client.GitClient = (*MockClient)(nil)
+var (
+	_ client.GitClient          = (*MockClient)(nil)
+	_ client.FileCreator        = (*MockClient)(nil)
+	_ client.PullRequestUpdater = (*MockClient)(nil)
+)
 
 // New creates and returns a new MockClient.
 func New(t *testing.T) *MockClient {
@@ -21,9 +24,12 @@ func New(t *testing.T) *MockClient {
 		t:                   t,
 		files:               make(map[string][]byte),
 		updatedFiles:        make(map[string][]byte),
+		createdFiles:        make(map[string][]byte),
 		createdBranches:     make(map[string]bool),
 		branchHeads:         make(map[string]string),
 		createdPullRequests: make(map[string][]*scm.PullRequestInput),
+		pullRequests:        make(map[string][]*scm.PullRequest),
+		updatedPullRequests: make(map[string]map[int]*scm.PullRequestInput),
 	}
 }
 
@@ -35,11 +41,16 @@ type MockClient struct {
 	GetFileErr           error
 	updatedFiles         map[string][]byte
 	UpdateFileErr        error
+	createdFiles         map[string][]byte
+	CreateFileErr        error
 	createdBranches      map[string]bool
 	CreateBranchErr      error
 	branchHeads          map[string]string
 	createdPullRequests  map[string][]*scm.PullRequestInput
 	CreatePullRequestErr error
+	pullRequests         map[string][]*scm.PullRequest
+	updatedPullRequests  map[string]map[int]*scm.PullRequestInput
+	UpdatePullRequestErr error
 }
 
 // GetFile implements the client.GitClient interface.
@@ -50,7 +61,7 @@ func (m *MockClient) GetFile(ctx context.Context, repo, ref, path string) (*scm.
 	if b, ok := m.files[key(repo, path, ref)]; ok {
 		return &scm.Content{Data: b, Sha: bytesSha1(b)}, nil
 	}
-	return nil, errors.New("not found")
+	return nil, scm.ErrNotFound
 }
 
 // UpdateFile implements the client.GitClient interface.
@@ -63,6 +74,15 @@ func (m *MockClient) UpdateFile(ctx context.Context, repo, branch, path, message
 	return nil
 }
 
+// CreateFile implements the client.FileCreator interface.
+func (m *MockClient) CreateFile(ctx context.Context, repo, branch, path, message string, content []byte) error {
+	if m.CreateFileErr != nil {
+		return m.CreateFileErr
+	}
+	m.createdFiles[key(repo, path, branch)] = content
+	return nil
+}
+
 // CreatePullRequest implements the client.GitClient interface.
 func (m *MockClient) CreatePullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
 	if m.CreatePullRequestErr != nil {
@@ -78,12 +98,50 @@ func (m *MockClient) CreatePullRequest(ctx context.Context, repo string, inp *sc
 	return &scm.PullRequest{Number: number, Link: fmt.Sprintf("https://example.com/pull-request/%d", number)}, nil
 }
 
+// FindPullRequest implements the client.PullRequestUpdater interface.
+//
+// Only PullRequests added with AddPullRequest are found.
+func (m *MockClient) FindPullRequest(ctx context.Context, repo, head, base string) (*scm.PullRequest, error) {
+	for _, pr := range m.pullRequests[repo] {
+		if pr.Source == head && pr.Target == base && !pr.Closed {
+			return pr, nil
+		}
+	}
+	return nil, scm.ErrNotFound
+}
+
+// UpdatePullRequest implements the client.PullRequestUpdater interface.
+func (m *MockClient) UpdatePullRequest(ctx context.Context, repo string, number int, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	if m.UpdatePullRequestErr != nil {
+		return nil, m.UpdatePullRequestErr
+	}
+	for _, pr := range m.pullRequests[repo] {
+		if pr.Number == number {
+			if m.updatedPullRequests[repo] == nil {
+				m.updatedPullRequests[repo] = make(map[int]*scm.PullRequestInput)
+			}
+			m.updatedPullRequests[repo][number] = inp
+			updated := *pr
+			updated.Title, updated.Body = inp.Title, inp.Body
+			return &updated, nil
+		}
+	}
+	return nil, scm.ErrNotFound
+}
+
 // CreateBranch implements the client.GitClient interface.
+//
+// Creating a branch that already exists fails, and created branches can be
+// found with GetBranchHead.
 func (m *MockClient) CreateBranch(ctx context.Context, repo, branch, sha string) error {
 	if m.CreateBranchErr != nil {
 		return m.CreateBranchErr
 	}
+	if _, ok := m.branchHeads[key(repo, branch)]; ok {
+		return fmt.Errorf("branch %s already exists in repo %s", branch, repo)
+	}
 	m.createdBranches[key(repo, branch, sha)] = true
+	m.branchHeads[key(repo, branch)] = sha
 	return nil
 }
 
@@ -91,7 +149,7 @@ func (m *MockClient) CreateBranch(ctx context.Context, repo, branch, sha string)
 func (m *MockClient) GetBranchHead(ctx context.Context, repo, branch string) (string, error) {
 	ref, ok := m.branchHeads[key(repo, branch)]
 	if !ok {
-		return "", errors.New("not found")
+		return "", scm.ErrNotFound
 	}
 	return ref, nil
 }
@@ -109,11 +167,34 @@ func (m *MockClient) GetUpdatedContents(repo, path, ref string) []byte {
 	return c
 }
 
+// GetCreatedContents returns the bytes captured by the mock implementation for
+// CreateFile.
+func (m *MockClient) GetCreatedContents(repo, path, ref string) []byte {
+	return m.createdFiles[key(repo, path, ref)]
+}
+
 // AddBranchHead is a mock for setting up a response for GetBranchHead.
+//
+// This can be used to simulate existing branches in the repo.
 func (m *MockClient) AddBranchHead(repo, branch, sha string) {
 	m.branchHeads[key(repo, branch)] = sha
 }
 
+// AddPullRequest is a mock for setting up an existing PullRequest that can be
+// found with FindPullRequest.
+func (m *MockClient) AddPullRequest(repo string, pr *scm.PullRequest) {
+	m.pullRequests[repo] = append(m.pullRequests[repo], pr)
+}
+
+// AssertPullRequestUpdated fails if the numbered PullRequest was not updated
+// with a matching input.
+func (m *MockClient) AssertPullRequestUpdated(repo string, number int, inp *scm.PullRequestInput) {
+	m.t.Helper()
+	if pr, ok := m.updatedPullRequests[repo][number]; !ok || !reflect.DeepEqual(inp, pr) {
+		m.t.Fatalf("pullrequest %d not updated in repo %s", number, repo)
+	}
+}
+
 // AssertBranchCreated fails if no matching branch was created using
 // CreateBranch.
 func (m *MockClient) AssertBranchCreated(repo, branch, sha string) {
@@ -175,6 +256,10 @@ func (m *MockClient) AssertNoInteractions() {
 		m.t.Fatalf("files were updated %#v", m.updatedFiles)
 	}
 
+	if len(m.createdFiles) != 0 {
+		m.t.Fatalf("files were created %#v", m.createdFiles)
+	}
+
 	if len(m.createdBranches) != 0 {
 		m.t.Fatalf("branches created %#v", m.createdBranches)
 	}
@@ -0,0 +1,3 @@
+// Package drift provides functionality for comparing the manifests in a Git
+// repository with the live objects in a cluster.
+package drift
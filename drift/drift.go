@@ -0,0 +1,214 @@
+package drift
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gitops-tools/pkg/applier"
+	"github.com/gitops-tools/pkg/client"
+	"github.com/google/go-cmp/cmp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Options configures the objects that are compared.
+type Options struct {
+	// Namespace is set on namespaced objects in the manifests that don't have
+	// a namespace.
+	Namespace string
+	// Inventory is the set of objects that were previously applied, e.g. from
+	// an applier.Report, objects in the Inventory that are not in the
+	// manifests, but exist in the cluster, are reported as only in the cluster.
+	Inventory []applier.ObjectRef
+	// Kinds are listed from the cluster with the Selector, and objects that
+	// are not in the manifests are reported as only in the cluster.
+	//
+	// Namespaced kinds are listed in the Namespace, or in all namespaces if
+	// the Namespace is not set.
+	Kinds []schema.GroupVersionKind
+	// Selector selects the objects that are listed for the Kinds, the default
+	// is to select every object.
+	Selector labels.Selector
+}
+
+// Difference is the difference between the object in Git and the live object.
+type Difference struct {
+	Object applier.ObjectRef
+	// Diff is a human-readable report of the differences, with lines removed
+	// from the Git object prefixed with "-", and lines added in the live
+	// object prefixed with "+".
+	Diff string
+}
+
+// Report is the result of comparing the manifests with the cluster.
+type Report struct {
+	OnlyInGit     []applier.ObjectRef
+	OnlyInCluster []applier.ObjectRef
+	Differences   []Difference
+}
+
+// HasDrift returns true if the cluster doesn't match the manifests.
+func (r Report) HasDrift() bool {
+	return len(r.OnlyInGit) > 0 || len(r.OnlyInCluster) > 0 || len(r.Differences) > 0
+}
+
+// Detector compares the manifests in Git repositories with the live objects
+// in a cluster.
+type Detector struct {
+	gitClient  client.GitClient
+	kubeClient ctrlclient.Client
+}
+
+// New creates and returns a Detector that reads manifests with the GitClient,
+// and live objects with the kube client, e.g. a client from
+// kubeconfig.ClientFromSecret.
+func New(gitClient client.GitClient, kubeClient ctrlclient.Client) *Detector {
+	return &Detector{
+		gitClient:  gitClient,
+		kubeClient: kubeClient,
+	}
+}
+
+// Detect reads the manifests at the paths in the repo at the ref, and compares
+// them with the live objects.
+func (d *Detector) Detect(ctx context.Context, repo, ref string, paths []string, opts Options) (*Report, error) {
+	var objects []*unstructured.Unstructured
+	for _, path := range paths {
+		content, err := d.gitClient.GetFile(ctx, repo, ref, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s from %s at %s: %w", path, repo, ref, err)
+		}
+		decoded, err := applier.Decode(content.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s from %s at %s: %w", path, repo, ref, err)
+		}
+		objects = append(objects, decoded...)
+	}
+	return d.Compare(ctx, objects, opts)
+}
+
+// Compare compares the objects with the live objects.
+//
+// Fields that are populated by the API server, and fields in the live objects
+// that are not in the desired objects e.g. defaulted fields, are ignored.
+// This means that fields removed from the manifests, that still exist in the
+// cluster, are not reported.
+func (d *Detector) Compare(ctx context.Context, objects []*unstructured.Unstructured, opts Options) (*Report, error) {
+	report := &Report{}
+	inGit := map[objectKey]bool{}
+	for _, obj := range objects {
+		obj = obj.DeepCopy()
+		if err := d.setDefaultNamespace(obj, opts.Namespace); err != nil {
+			return nil, err
+		}
+		ref := applier.ObjectRefFromObject(obj)
+		inGit[keyForRef(ref)] = true
+
+		live, err := d.get(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if live == nil {
+			report.OnlyInGit = append(report.OnlyInGit, ref)
+			continue
+		}
+
+		desired := normalize(obj)
+		if diff := cmp.Diff(desired, project(desired, normalize(live))); diff != "" {
+			report.Differences = append(report.Differences, Difference{Object: ref, Diff: diff})
+		}
+	}
+
+	onlyInCluster := func(ref applier.ObjectRef) {
+		key := keyForRef(ref)
+		if !inGit[key] {
+			inGit[key] = true
+			report.OnlyInCluster = append(report.OnlyInCluster, ref)
+		}
+	}
+	for _, ref := range opts.Inventory {
+		if inGit[keyForRef(ref)] {
+			continue
+		}
+		live, err := d.get(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if live != nil {
+			onlyInCluster(ref)
+		}
+	}
+	selector := opts.Selector
+	if selector == nil {
+		selector = labels.Everything()
+	}
+	for _, gvk := range opts.Kinds {
+		listOpts := []ctrlclient.ListOption{ctrlclient.MatchingLabelsSelector{Selector: selector}}
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		namespaced, err := d.kubeClient.IsObjectNamespaced(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check if %s is namespaced: %w", gvk.Kind, err)
+		}
+		if namespaced {
+			listOpts = append(listOpts, ctrlclient.InNamespace(opts.Namespace))
+		}
+
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := d.kubeClient.List(ctx, list, listOpts...); err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", gvk.Kind, err)
+		}
+		for i := range list.Items {
+			onlyInCluster(applier.ObjectRefFromObject(&list.Items[i]))
+		}
+	}
+
+	return report, nil
+}
+
+// get returns nil if the object doesn't exist.
+func (d *Detector) get(ctx context.Context, ref applier.ObjectRef) (*unstructured.Unstructured, error) {
+	live := &unstructured.Unstructured{}
+	live.SetAPIVersion(ref.APIVersion)
+	live.SetKind(ref.Kind)
+	if err := d.kubeClient.Get(ctx, ctrlclient.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, live); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get %s: %w", ref, err)
+	}
+	return live, nil
+}
+
+func (d *Detector) setDefaultNamespace(obj *unstructured.Unstructured, namespace string) error {
+	if obj.GetNamespace() != "" || namespace == "" {
+		return nil
+	}
+	namespaced, err := d.kubeClient.IsObjectNamespaced(obj)
+	if err != nil {
+		return fmt.Errorf("failed to check if %s is namespaced: %w", applier.ObjectRefFromObject(obj), err)
+	}
+	if namespaced {
+		obj.SetNamespace(namespace)
+	}
+	return nil
+}
+
+// objectKey identifies objects by group and kind, so that objects are matched
+// when their apiVersion changes.
+type objectKey struct {
+	groupKind       schema.GroupKind
+	namespace, name string
+}
+
+func keyForRef(ref applier.ObjectRef) objectKey {
+	return objectKey{
+		groupKind: schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind).GroupKind(),
+		namespace: ref.Namespace,
+		name:      ref.Name,
+	}
+}
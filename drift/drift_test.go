@@ -0,0 +1,223 @@
+package drift
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gitops-tools/pkg/applier"
+	"github.com/gitops-tools/pkg/client/mock"
+	"github.com/gitops-tools/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	testRepo = "testing/repo"
+	testRef  = "main"
+)
+
+const testManifests = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  key: value
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    app: test
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: test
+  template:
+    metadata:
+      labels:
+        app: test
+    spec:
+      containers:
+      - name: app
+        image: app:v1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: missing-config
+`
+
+func TestDetect(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testRepo, "deploy/app.yaml", testRef, []byte(testManifests))
+	cl := newFakeClient(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "app-config",
+				Namespace:       "test",
+				ResourceVersion: "10",
+			},
+			Data: map[string]string{"key": "value"},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "app",
+				Namespace:   "test",
+				Labels:      map[string]string{"app": "test"},
+				Annotations: map[string]string{"deployment.kubernetes.io/revision": "2"},
+				Generation:  2,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas:             ptr.To[int32](3),
+				RevisionHistoryLimit: ptr.To[int32](10),
+				Selector:             &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test"}},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Name: "app", Image: "app:v1", ImagePullPolicy: corev1.PullIfNotPresent},
+						},
+					},
+				},
+			},
+			Status: appsv1.DeploymentStatus{Replicas: 3, ObservedGeneration: 2},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "orphan-config", Namespace: "test", Labels: map[string]string{"app": "test"}},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "unmanaged-config", Namespace: "test"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "old-secret", Namespace: "test"},
+		},
+	)
+	d := New(m, cl)
+	oldSecretRef := applier.ObjectRef{APIVersion: "v1", Kind: "Secret", Namespace: "test", Name: "old-secret"}
+	deletedRef := applier.ObjectRef{APIVersion: "v1", Kind: "Secret", Namespace: "test", Name: "deleted-secret"}
+
+	report, err := d.Detect(context.TODO(), testRepo, testRef, []string{"deploy/app.yaml"}, Options{
+		Namespace: "test",
+		Inventory: []applier.ObjectRef{oldSecretRef, deletedRef},
+		Kinds:     []schema.GroupVersionKind{{Version: "v1", Kind: "ConfigMap"}},
+		Selector:  labels.SelectorFromSet(labels.Set{"app": "test"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.HasDrift() {
+		t.Fatal("failed to detect drift")
+	}
+	want := []applier.ObjectRef{{APIVersion: "v1", Kind: "ConfigMap", Namespace: "test", Name: "missing-config"}}
+	if diff := cmp.Diff(want, report.OnlyInGit); diff != "" {
+		t.Errorf("failed to report objects only in Git:\n%s", diff)
+	}
+	want = []applier.ObjectRef{oldSecretRef, {APIVersion: "v1", Kind: "ConfigMap", Namespace: "test", Name: "orphan-config"}}
+	if diff := cmp.Diff(want, report.OnlyInCluster); diff != "" {
+		t.Errorf("failed to report objects only in the cluster:\n%s", diff)
+	}
+	if len(report.Differences) != 1 {
+		t.Fatalf("got %d differences, want 1", len(report.Differences))
+	}
+	difference := report.Differences[0]
+	if difference.Object.Name != "app" {
+		t.Errorf("got difference for %s, want Deployment/test/app", difference.Object)
+	}
+	for _, s := range []string{`"replicas": int64(2)`, `"replicas": int64(3)`} {
+		if !strings.Contains(difference.Diff, s) {
+			t.Errorf("diff does not contain %s:\n%s", s, difference.Diff)
+		}
+	}
+}
+
+func TestDetectNoDrift(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testRepo, "deploy/config.yaml", testRef, []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\n  namespace: test\ndata:\n  key: value\n"))
+	cl := newFakeClient(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "test", Annotations: map[string]string{"example.com/note": "added"}},
+		Data:       map[string]string{"key": "value"},
+	})
+
+	report, err := New(m, cl).Detect(context.TODO(), testRepo, testRef, []string{"deploy/config.yaml"}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.HasDrift() {
+		t.Fatalf("got drift %#v", report)
+	}
+}
+
+func TestDetectWithoutSelector(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testRepo, "deploy/config.yaml", testRef, []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\n"))
+	cl := newFakeClient(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "test"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unmanaged-config", Namespace: "test"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other-config", Namespace: "other"}},
+	)
+
+	report, err := New(m, cl).Detect(context.TODO(), testRepo, testRef, []string{"deploy/config.yaml"}, Options{
+		Namespace: "test",
+		Kinds: []schema.GroupVersionKind{
+			{Version: "v1", Kind: "ConfigMap"},
+			{Version: "v1", Kind: "Namespace"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []applier.ObjectRef{
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "test", Name: "unmanaged-config"},
+		{APIVersion: "v1", Kind: "Namespace", Name: "test"},
+	}
+	if diff := cmp.Diff(want, report.OnlyInCluster); diff != "" {
+		t.Errorf("failed to report objects only in the cluster:\n%s", diff)
+	}
+}
+
+func TestDetectErrors(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testRepo, "deploy/invalid.yaml", testRef, []byte("kind: [\n"))
+	d := New(m, newFakeClient())
+
+	errorTests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{name: "missing file", path: "deploy/missing.yaml", wantErr: "failed to get deploy/missing.yaml from testing/repo at main: Not Found"},
+		{name: "invalid file", path: "deploy/invalid.yaml", wantErr: "failed to decode deploy/invalid.yaml from testing/repo at main: failed to decode document 1"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := d.Detect(context.TODO(), testRepo, testRef, []string{tt.path}, Options{})
+			if !test.MatchError(t, tt.wantErr, err) {
+				t.Fatalf("failed to match error %v against %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func newFakeClient(objs ...ctrlclient.Object) ctrlclient.Client {
+	return fake.NewClientBuilder().
+		WithRESTMapper(testrestmapper.TestOnlyStaticRESTMapper(clientgoscheme.Scheme)).
+		WithObjects(objs...).
+		Build()
+}
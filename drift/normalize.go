@@ -0,0 +1,73 @@
+package drift
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// serverFields are populated by the API server, and are removed from both the
+// desired and live objects before they are compared.
+var serverFields = [][]string{
+	{"status"},
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "uid"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "deletionTimestamp"},
+	{"metadata", "deletionGracePeriodSeconds"},
+	{"metadata", "selfLink"},
+	{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+	{"metadata", "annotations", "deployment.kubernetes.io/revision"},
+}
+
+// normalize returns a copy of the object without the fields populated by the
+// API server, and without empty labels and annotations.
+func normalize(obj *unstructured.Unstructured) map[string]any {
+	normalized := obj.DeepCopy()
+	for _, field := range serverFields {
+		unstructured.RemoveNestedField(normalized.Object, field...)
+	}
+	for _, field := range []string{"labels", "annotations"} {
+		if m, _, _ := unstructured.NestedMap(normalized.Object, "metadata", field); len(m) == 0 {
+			unstructured.RemoveNestedField(normalized.Object, "metadata", field)
+		}
+	}
+	return normalized.Object
+}
+
+// project returns the fields of the live value that are in the desired value,
+// so that fields that are defaulted by the API server are not reported as
+// differences.
+//
+// Maps are projected by key, and lists by index, live list items that are not
+// in the desired list are kept, so that they are reported.
+func project(desired, live any) any {
+	switch d := desired.(type) {
+	case map[string]any:
+		l, ok := live.(map[string]any)
+		if !ok {
+			return live
+		}
+		projected := make(map[string]any, len(d))
+		for k, dv := range d {
+			if lv, ok := l[k]; ok {
+				projected[k] = project(dv, lv)
+			}
+		}
+		return projected
+	case []any:
+		l, ok := live.([]any)
+		if !ok {
+			return live
+		}
+		projected := make([]any, len(l))
+		for i, lv := range l {
+			if i < len(d) {
+				lv = project(d[i], lv)
+			}
+			projected[i] = lv
+		}
+		return projected
+	}
+	return live
+}
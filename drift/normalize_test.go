@@ -0,0 +1,86 @@
+package drift
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestNormalize(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]any{
+			"name":              "test",
+			"uid":               "test-uid",
+			"resourceVersion":   "10",
+			"creationTimestamp": "2026-10-18T12:00:00Z",
+			"managedFields":     []any{map[string]any{"manager": "test"}},
+			"labels":            map[string]any{},
+			"annotations": map[string]any{
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
+		},
+		"data":   map[string]any{"key": "value"},
+		"status": map[string]any{"phase": "Ready"},
+	}}
+
+	want := map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]any{"name": "test"},
+		"data":       map[string]any{"key": "value"},
+	}
+	if diff := cmp.Diff(want, normalize(obj)); diff != "" {
+		t.Fatalf("failed to normalize:\n%s", diff)
+	}
+}
+
+func TestProject(t *testing.T) {
+	projectTests := []struct {
+		name    string
+		desired any
+		live    any
+		want    any
+	}{
+		{
+			name:    "defaulted map fields",
+			desired: map[string]any{"replicas": int64(1)},
+			live:    map[string]any{"replicas": int64(1), "revisionHistoryLimit": int64(10)},
+			want:    map[string]any{"replicas": int64(1)},
+		},
+		{
+			name:    "missing field",
+			desired: map[string]any{"replicas": int64(1)},
+			live:    map[string]any{},
+			want:    map[string]any{},
+		},
+		{
+			name:    "list items",
+			desired: []any{map[string]any{"name": "test"}},
+			live:    []any{map[string]any{"name": "test", "imagePullPolicy": "Always"}},
+			want:    []any{map[string]any{"name": "test"}},
+		},
+		{
+			name:    "extra live list items",
+			desired: []any{map[string]any{"name": "test"}},
+			live:    []any{map[string]any{"name": "test", "imagePullPolicy": "Always"}, map[string]any{"name": "sidecar"}},
+			want:    []any{map[string]any{"name": "test"}, map[string]any{"name": "sidecar"}},
+		},
+		{
+			name:    "changed type",
+			desired: map[string]any{"value": map[string]any{"key": "value"}},
+			live:    map[string]any{"value": "string"},
+			want:    map[string]any{"value": "string"},
+		},
+	}
+
+	for _, tt := range projectTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, project(tt.desired, tt.live)); diff != "" {
+				t.Fatalf("failed to project:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultKubeConfigKey is the key that the KubeConfig is read from when the
+// format is auto-detected and no Key is provided.
+const DefaultKubeConfigKey = "kubeconfig"
+
+const (
+	clusterAPIKey           = "value"
+	clusterAPISecretType    = corev1.SecretType("cluster.x-k8s.io/secret")
+	clusterAPIClusterLabel  = "cluster.x-k8s.io/cluster-name"
+	argoCDSecretTypeLabel   = "argocd.argoproj.io/secret-type"
+	argoCDClusterSecretType = "cluster"
+	argoCDServerKey         = "server"
+	argoCDConfigKey         = "config"
+)
+
+// SecretFormat parses a rest.Config from a Secret in a specific format.
+type SecretFormat interface {
+	// Detect returns true if the Secret looks like it is in this format.
+	Detect(secret *corev1.Secret) bool
+	// RESTConfig parses the rest.Config from the Secret.
+	RESTConfig(secret *corev1.Secret) (*rest.Config, error)
+}
+
+// KubeConfigFormat parses a KubeConfig from a key in the Secret.
+type KubeConfigFormat struct {
+	Key string
+	// Policy restricts the KubeConfig features that can be loaded.
+	// If this is not provided, all features are allowed.
+	Policy *LoadingPolicy
+}
+
+// Detect returns true if the Secret has the Key.
+func (f KubeConfigFormat) Detect(secret *corev1.Secret) bool {
+	return len(secret.Data[f.Key]) > 0
+}
+
+// RESTConfig parses the rest.Config from the KubeConfig in the Key.
+func (f KubeConfigFormat) RESTConfig(secret *corev1.Secret) (*rest.Config, error) {
+	b := secret.Data[f.Key]
+	if len(b) == 0 {
+		return nil, fmt.Errorf("KubeConfig secret %q doesn't contain a KubeConfig, missing key %q", client.ObjectKeyFromObject(secret), f.Key)
+	}
+
+	cfg, err := restConfigFromKubeConfig(b, f.Policy)
+	var policyErr *policyError
+	if errors.As(err, &policyErr) {
+		return nil, fmt.Errorf("KubeConfig secret %q is not allowed by the loading policy: %w", client.ObjectKeyFromObject(secret), err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse REST Config from secret data: %w", err)
+	}
+	return cfg, nil
+}
+
+// ClusterAPIFormat parses the KubeConfig from a Cluster API <cluster>-kubeconfig
+// Secret, which stores the KubeConfig in the "value" key.
+type ClusterAPIFormat struct {
+	// Policy restricts the KubeConfig features that can be loaded.
+	// If this is not provided, all features are allowed.
+	Policy *LoadingPolicy
+}
+
+// Detect returns true if the Secret has the Cluster API secret type or cluster
+// name label, and a "value" key.
+func (f ClusterAPIFormat) Detect(secret *corev1.Secret) bool {
+	if len(secret.Data[clusterAPIKey]) == 0 {
+		return false
+	}
+	_, hasLabel := secret.Labels[clusterAPIClusterLabel]
+	return secret.Type == clusterAPISecretType || hasLabel
+}
+
+// RESTConfig parses the rest.Config from the KubeConfig in the "value" key.
+func (f ClusterAPIFormat) RESTConfig(secret *corev1.Secret) (*rest.Config, error) {
+	return KubeConfigFormat{Key: clusterAPIKey, Policy: f.Policy}.RESTConfig(secret)
+}
+
+// ArgoCDFormat parses an Argo CD cluster Secret, which stores the API server
+// URL in the "server" key, and the credentials as JSON in the "config" key.
+//
+// Clusters that authenticate with execProviderConfig or awsAuthConfig are not
+// supported.
+type ArgoCDFormat struct{}
+
+// argoCDClusterConfig is the subset of the Argo CD ClusterConfig that is
+// supported.
+type argoCDClusterConfig struct {
+	Username        string `json:"username,omitempty"`
+	Password        string `json:"password,omitempty"`
+	BearerToken     string `json:"bearerToken,omitempty"`
+	TLSClientConfig struct {
+		Insecure   bool   `json:"insecure,omitempty"`
+		ServerName string `json:"serverName,omitempty"`
+		CertData   []byte `json:"certData,omitempty"`
+		KeyData    []byte `json:"keyData,omitempty"`
+		CAData     []byte `json:"caData,omitempty"`
+	} `json:"tlsClientConfig"`
+	ExecProviderConfig json.RawMessage `json:"execProviderConfig,omitempty"`
+	AWSAuthConfig      json.RawMessage `json:"awsAuthConfig,omitempty"`
+}
+
+// Detect returns true if the Secret has the Argo CD cluster secret-type
+// label, or both the "server" and "config" keys.
+func (f ArgoCDFormat) Detect(secret *corev1.Secret) bool {
+	if secret.Labels[argoCDSecretTypeLabel] == argoCDClusterSecretType {
+		return true
+	}
+	return len(secret.Data[argoCDServerKey]) > 0 && len(secret.Data[argoCDConfigKey]) > 0
+}
+
+// RESTConfig builds a rest.Config from the "server" and "config" keys.
+func (f ArgoCDFormat) RESTConfig(secret *corev1.Secret) (*rest.Config, error) {
+	name := client.ObjectKeyFromObject(secret)
+	server := string(secret.Data[argoCDServerKey])
+	if server == "" {
+		return nil, fmt.Errorf("Argo CD cluster secret %q doesn't contain a server, missing key %q", name, argoCDServerKey)
+	}
+
+	var config argoCDClusterConfig
+	if b := secret.Data[argoCDConfigKey]; len(b) > 0 {
+		if err := json.Unmarshal(b, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse config from Argo CD cluster secret %q: %w", name, err)
+		}
+	}
+	if len(config.ExecProviderConfig) > 0 || len(config.AWSAuthConfig) > 0 {
+		return nil, fmt.Errorf("Argo CD cluster secret %q uses an unsupported exec provider or AWS authentication", name)
+	}
+
+	return &rest.Config{
+		Host:        server,
+		Username:    config.Username,
+		Password:    config.Password,
+		BearerToken: config.BearerToken,
+		TLSClientConfig: rest.TLSClientConfig{
+			Insecure:   config.TLSClientConfig.Insecure,
+			ServerName: config.TLSClientConfig.ServerName,
+			CertData:   config.TLSClientConfig.CertData,
+			KeyData:    config.TLSClientConfig.KeyData,
+			CAData:     config.TLSClientConfig.CAData,
+		},
+	}, nil
+}
+
+// AutoDetectFormat returns a SecretFormat that parses Secrets with the first
+// of the formats that detects the Secret.
+//
+// If no formats are provided, the Cluster API, Argo CD and KubeConfig formats
+// are detected, with the KubeConfig read from the DefaultKubeConfigKey.
+func AutoDetectFormat(formats ...SecretFormat) SecretFormat {
+	if len(formats) == 0 {
+		formats = []SecretFormat{ClusterAPIFormat{}, ArgoCDFormat{}, KubeConfigFormat{Key: DefaultKubeConfigKey}}
+	}
+	return autoDetectFormat{formats: formats}
+}
+
+type autoDetectFormat struct {
+	formats []SecretFormat
+}
+
+func (f autoDetectFormat) Detect(secret *corev1.Secret) bool {
+	return f.detect(secret) != nil
+}
+
+func (f autoDetectFormat) RESTConfig(secret *corev1.Secret) (*rest.Config, error) {
+	format := f.detect(secret)
+	if format == nil {
+		return nil, fmt.Errorf("KubeConfig secret %q is not in a known format", client.ObjectKeyFromObject(secret))
+	}
+	return format.RESTConfig(secret)
+}
+
+func (f autoDetectFormat) detect(secret *corev1.Secret) SecretFormat {
+	for _, format := range f.formats {
+		if format.Detect(secret) {
+			return format
+		}
+	}
+	return nil
+}
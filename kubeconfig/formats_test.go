@@ -0,0 +1,179 @@
+package kubeconfig
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var testSecretName = types.NamespacedName{Namespace: "default", Name: "test-kubeconfig"}
+
+func TestSecretFormats(t *testing.T) {
+	kubeConfig := generateKubeConfig(t, "default", &rest.Config{Host: "https://cluster.example.com", BearerToken: "test-token"})
+
+	formatTests := []struct {
+		name   string
+		format SecretFormat
+		secret *corev1.Secret
+		want   *rest.Config
+	}{
+		{
+			name:   "kubeconfig",
+			format: KubeConfigFormat{Key: "kube.config"},
+			secret: newTestSecret(nil, map[string][]byte{"kube.config": kubeConfig}),
+			want:   &rest.Config{Host: "https://cluster.example.com", BearerToken: "test-token"},
+		},
+		{
+			name:   "Cluster API",
+			format: ClusterAPIFormat{},
+			secret: newTestSecret(map[string]string{"cluster.x-k8s.io/cluster-name": "test"}, map[string][]byte{"value": kubeConfig}),
+			want:   &rest.Config{Host: "https://cluster.example.com", BearerToken: "test-token"},
+		},
+		{
+			name:   "Argo CD",
+			format: ArgoCDFormat{},
+			secret: newTestSecret(map[string]string{"argocd.argoproj.io/secret-type": "cluster"}, map[string][]byte{
+				"server": []byte("https://cluster.example.com"),
+				"config": []byte(`{"bearerToken":"test-token","tlsClientConfig":{"serverName":"cluster","caData":"dGVzdC1jYQ=="}}`),
+			}),
+			want: &rest.Config{
+				Host:            "https://cluster.example.com",
+				BearerToken:     "test-token",
+				TLSClientConfig: rest.TLSClientConfig{ServerName: "cluster", CAData: []byte("test-ca")},
+			},
+		},
+		{
+			name:   "auto-detected Argo CD",
+			format: AutoDetectFormat(),
+			secret: newTestSecret(nil, map[string][]byte{
+				"server": []byte("https://cluster.example.com"),
+				"config": []byte(`{"username":"test-user","password":"test-password","tlsClientConfig":{"insecure":true}}`),
+			}),
+			want: &rest.Config{
+				Host:            "https://cluster.example.com",
+				Username:        "test-user",
+				Password:        "test-password",
+				TLSClientConfig: rest.TLSClientConfig{Insecure: true},
+			},
+		},
+		{
+			name:   "auto-detected Cluster API",
+			format: AutoDetectFormat(),
+			secret: &corev1.Secret{Type: "cluster.x-k8s.io/secret", Data: map[string][]byte{"value": kubeConfig}},
+			want:   &rest.Config{Host: "https://cluster.example.com", BearerToken: "test-token"},
+		},
+		{
+			name:   "auto-detected kubeconfig",
+			format: AutoDetectFormat(),
+			secret: newTestSecret(nil, map[string][]byte{"kubeconfig": kubeConfig}),
+			want:   &rest.Config{Host: "https://cluster.example.com", BearerToken: "test-token"},
+		},
+	}
+
+	for _, tt := range formatTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.format.Detect(tt.secret) {
+				t.Fatal("failed to detect secret format")
+			}
+			cfg, err := tt.format.RESTConfig(tt.secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want.Host, cfg.Host); diff != "" {
+				t.Fatalf("failed to parse host:\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.want.TLSClientConfig, cfg.TLSClientConfig); diff != "" {
+				t.Fatalf("failed to parse TLS config:\n%s", diff)
+			}
+			got := []string{cfg.BearerToken, cfg.Username, cfg.Password}
+			if diff := cmp.Diff([]string{tt.want.BearerToken, tt.want.Username, tt.want.Password}, got); diff != "" {
+				t.Fatalf("failed to parse credentials:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSecretFormatErrors(t *testing.T) {
+	errorTests := []struct {
+		name    string
+		format  SecretFormat
+		secret  *corev1.Secret
+		wantErr string
+	}{
+		{
+			name:    "missing key",
+			format:  KubeConfigFormat{Key: "kubeconfig"},
+			secret:  newTestSecret(nil, nil),
+			wantErr: `KubeConfig secret "default/test-kubeconfig" doesn't contain a KubeConfig, missing key "kubeconfig"`,
+		},
+		{
+			name:    "missing Argo CD server",
+			format:  ArgoCDFormat{},
+			secret:  newTestSecret(nil, map[string][]byte{"config": []byte("{}")}),
+			wantErr: `Argo CD cluster secret "default/test-kubeconfig" doesn't contain a server, missing key "server"`,
+		},
+		{
+			name:    "invalid Argo CD config",
+			format:  ArgoCDFormat{},
+			secret:  newTestSecret(nil, map[string][]byte{"server": []byte("https://cluster.example.com"), "config": []byte("{")}),
+			wantErr: `failed to parse config from Argo CD cluster secret "default/test-kubeconfig": unexpected end of JSON input`,
+		},
+		{
+			name:   "Argo CD exec provider",
+			format: ArgoCDFormat{},
+			secret: newTestSecret(nil, map[string][]byte{
+				"server": []byte("https://cluster.example.com"),
+				"config": []byte(`{"execProviderConfig":{"command":"argocd-k8s-auth"}}`),
+			}),
+			wantErr: `Argo CD cluster secret "default/test-kubeconfig" uses an unsupported exec provider or AWS authentication`,
+		},
+		{
+			name:    "unknown format",
+			format:  AutoDetectFormat(),
+			secret:  newTestSecret(nil, map[string][]byte{"token": []byte("test")}),
+			wantErr: `KubeConfig secret "default/test-kubeconfig" is not in a known format`,
+		},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.format.RESTConfig(tt.secret)
+			assertErrorMatch(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestRESTConfigFromSecretWithFormat(t *testing.T) {
+	cl := fake.NewClientBuilder().WithObjects(newTestSecret(nil, map[string][]byte{
+		"server": []byte("https://cluster.example.com"),
+		"config": []byte(`{"bearerToken":"test-token"}`),
+	})).Build()
+
+	cfg, err := restConfigFromSecret(context.TODO(), cl, testSecretName, ClientOptions{
+		REST: RESTOptions{Impersonate: &rest.ImpersonationConfig{UserName: "test-user"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Host != "https://cluster.example.com" || cfg.Impersonate.UserName != "test-user" {
+		t.Fatalf("got host %s impersonating %s", cfg.Host, cfg.Impersonate.UserName)
+	}
+}
+
+func newTestSecret(labels map[string]string, data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testSecretName.Name,
+			Namespace: testSecretName.Namespace,
+			Labels:    labels,
+		},
+		Data: data,
+	}
+}
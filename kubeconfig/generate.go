@@ -0,0 +1,295 @@
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gitops-tools/pkg/secrets"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TokenExpirationAnnotation is set on generated KubeConfig secrets to the time
+// that the token in the KubeConfig expires, in RFC3339 format.
+const TokenExpirationAnnotation = "gitops-tools/token-expiration"
+
+// TokenRefreshAnnotation is set on generated KubeConfig secrets to the time
+// that the token in the KubeConfig should be refreshed, in RFC3339 format.
+const TokenRefreshAnnotation = "gitops-tools/token-refresh"
+
+const (
+	defaultTokenExpiration = time.Hour
+	defaultRefreshBefore   = time.Minute * 10
+	// minRefreshInterval is the shortest time that Refresh returns, so that
+	// reconcilers always requeue.
+	minRefreshInterval = time.Second * 30
+)
+
+// KubeConfigGenerator generates KubeConfig secrets that authenticate as a
+// ServiceAccount with a bound token from the TokenRequest API.
+//
+// The secrets are written in the format that ClientFromSecret reads.
+type KubeConfigGenerator struct {
+	cfg           *rest.Config
+	kubeClient    client.Client
+	tokenClient   client.Client
+	setter        secrets.SecretSetter
+	key           string
+	server        string
+	audiences     []string
+	expiration    time.Duration
+	refreshBefore time.Duration
+	clock         func() time.Time
+}
+
+// GeneratorOption is an option func for the KubeConfigGenerator creation
+// function.
+type GeneratorOption func(g *KubeConfigGenerator)
+
+// GeneratedKey is an option func for the KubeConfigGenerator creation function
+// that replaces the DefaultKubeConfigKey that the KubeConfig is written to.
+func GeneratedKey(key string) GeneratorOption {
+	return func(g *KubeConfigGenerator) {
+		g.key = key
+	}
+}
+
+// Server is an option func for the KubeConfigGenerator creation function that
+// replaces the server in the generated KubeConfig, for when the cluster is
+// reached through a different URL by the clients that read the KubeConfig.
+func Server(url string) GeneratorOption {
+	return func(g *KubeConfigGenerator) {
+		g.server = url
+	}
+}
+
+// TokenAudiences is an option func for the KubeConfigGenerator creation
+// function that sets the audiences of the requested tokens.
+//
+// If no audiences are provided, the API server audiences are used.
+func TokenAudiences(audiences ...string) GeneratorOption {
+	return func(g *KubeConfigGenerator) {
+		g.audiences = audiences
+	}
+}
+
+// TokenExpiration is an option func for the KubeConfigGenerator creation
+// function that sets the requested lifetime of the tokens, the default is one
+// hour.
+//
+// The API server can issue tokens with a different lifetime.
+func TokenExpiration(d time.Duration) GeneratorOption {
+	return func(g *KubeConfigGenerator) {
+		g.expiration = d
+	}
+}
+
+// RefreshBefore is an option func for the KubeConfigGenerator creation
+// function that sets how long before the token expires the secret is
+// refreshed, the default is ten minutes.
+func RefreshBefore(d time.Duration) GeneratorOption {
+	return func(g *KubeConfigGenerator) {
+		g.refreshBefore = d
+	}
+}
+
+// TokenClient is an option func for the KubeConfigGenerator creation function
+// that provides the client used to request tokens, instead of creating one
+// from the rest.Config.
+func TokenClient(cl client.Client) GeneratorOption {
+	return func(g *KubeConfigGenerator) {
+		g.tokenClient = cl
+	}
+}
+
+// GeneratorClock is an option func for the KubeConfigGenerator creation
+// function that replaces the clock used to check if tokens need refreshing.
+func GeneratorClock(f func() time.Time) GeneratorOption {
+	return func(g *KubeConfigGenerator) {
+		g.clock = f
+	}
+}
+
+// NewKubeConfigGenerator creates and returns a KubeConfigGenerator that
+// requests tokens from the cluster in the rest.Config, and renders the
+// server and CA from it.
+//
+// Generated secrets are read with the client, and written with the setter.
+func NewKubeConfigGenerator(cfg *rest.Config, cl client.Client, setter secrets.SecretSetter, opts ...GeneratorOption) (*KubeConfigGenerator, error) {
+	g := &KubeConfigGenerator{
+		cfg:           cfg,
+		kubeClient:    cl,
+		setter:        setter,
+		key:           DefaultKubeConfigKey,
+		server:        cfg.Host,
+		expiration:    defaultTokenExpiration,
+		refreshBefore: defaultRefreshBefore,
+		clock:         time.Now,
+	}
+	for _, o := range opts {
+		o(g)
+	}
+	if g.tokenClient == nil {
+		tokenClient, err := client.New(cfg, client.Options{Scheme: runtimeScheme})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create a client: %w", err)
+		}
+		g.tokenClient = tokenClient
+	}
+	return g, nil
+}
+
+// Generate requests a token for the ServiceAccount and returns a KubeConfig
+// that authenticates with it, and the time that the API server reports the
+// token expires.
+func (g *KubeConfigGenerator) Generate(ctx context.Context, serviceAccount types.NamespacedName) ([]byte, time.Time, error) {
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      serviceAccount.Name,
+			Namespace: serviceAccount.Namespace,
+		},
+	}
+	expirationSeconds := int64(g.expiration.Seconds())
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         g.audiences,
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}
+	if err := g.tokenClient.SubResource("token").Create(ctx, sa, tokenRequest); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to request a token for ServiceAccount %q: %w", serviceAccount, err)
+	}
+
+	b, err := g.render(serviceAccount, tokenRequest.Status.Token)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	expiration := tokenRequest.Status.ExpirationTimestamp.Time
+	if expiration.IsZero() {
+		expiration = g.clock().Add(g.expiration)
+	}
+	return b, expiration, nil
+}
+
+// Write generates a KubeConfig for the ServiceAccount, and writes it to the
+// named secret, annotated with the TokenExpirationAnnotation and
+// TokenRefreshAnnotation.
+func (g *KubeConfigGenerator) Write(ctx context.Context, serviceAccount, secretName types.NamespacedName) (time.Time, error) {
+	expiration, _, err := g.write(ctx, serviceAccount, secretName)
+	return expiration, err
+}
+
+// write returns the expiration and refresh time of the written token.
+func (g *KubeConfigGenerator) write(ctx context.Context, serviceAccount, secretName types.NamespacedName) (time.Time, time.Time, error) {
+	issued := g.clock()
+	b, expiration, err := g.Generate(ctx, serviceAccount)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	refreshAt := g.refreshTime(issued, expiration)
+	err = g.setter.SetSecret(ctx, secretName, map[string]string{g.key: string(b)}, secrets.SetOptions{
+		Annotations: map[string]string{
+			TokenExpirationAnnotation: expiration.UTC().Format(time.RFC3339),
+			TokenRefreshAnnotation:    refreshAt.UTC().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return expiration, refreshAt, nil
+}
+
+// Refresh writes the KubeConfig secret if it doesn't exist, or the token in it
+// is due to expire, and returns the time until it next needs refreshing, for
+// use as the RequeueAfter in a reconciler.
+//
+// If the API server issues tokens with a lifetime shorter than twice
+// RefreshBefore, the token is refreshed when half of its lifetime has passed.
+//
+// The returned time is never less than thirty seconds.
+func (g *KubeConfigGenerator) Refresh(ctx context.Context, serviceAccount, secretName types.NamespacedName) (time.Duration, error) {
+	refreshAt, err := g.tokenRefreshTime(ctx, secretName)
+	if err != nil {
+		return 0, err
+	}
+	if refreshAt.IsZero() || !g.clock().Before(refreshAt) {
+		_, refreshAt, err = g.write(ctx, serviceAccount, secretName)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return max(refreshAt.Sub(g.clock()), minRefreshInterval), nil
+}
+
+// refreshTime returns the time that a token issued at the issued time, that
+// expires at the expiration, should be refreshed.
+func (g *KubeConfigGenerator) refreshTime(issued, expiration time.Time) time.Time {
+	return expiration.Add(-min(g.refreshBefore, expiration.Sub(issued)/2))
+}
+
+// tokenRefreshTime returns the zero time if the secret doesn't exist, or the
+// annotations are missing or invalid.
+//
+// Secrets without the TokenRefreshAnnotation are refreshed RefreshBefore the
+// TokenExpirationAnnotation.
+func (g *KubeConfigGenerator) tokenRefreshTime(ctx context.Context, secretName types.NamespacedName) (time.Time, error) {
+	var secret corev1.Secret
+	if err := g.kubeClient.Get(ctx, secretName, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("unable to read KubeConfig secret %q error: %w", secretName, err)
+	}
+	if len(secret.Data[g.key]) == 0 {
+		return time.Time{}, nil
+	}
+	if refreshAt, err := time.Parse(time.RFC3339, secret.Annotations[TokenRefreshAnnotation]); err == nil {
+		return refreshAt, nil
+	}
+	expiration, err := time.Parse(time.RFC3339, secret.Annotations[TokenExpirationAnnotation])
+	if err != nil {
+		return time.Time{}, nil
+	}
+	return expiration.Add(-g.refreshBefore), nil
+}
+
+func (g *KubeConfigGenerator) render(serviceAccount types.NamespacedName, token string) ([]byte, error) {
+	tlsConfig := rest.CopyConfig(g.cfg)
+	if err := rest.LoadTLSFiles(tlsConfig); err != nil {
+		return nil, fmt.Errorf("failed to load the CA for the cluster: %w", err)
+	}
+
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = g.server
+	cluster.CertificateAuthorityData = tlsConfig.CAData
+	cluster.TLSServerName = tlsConfig.ServerName
+	cluster.InsecureSkipTLSVerify = tlsConfig.Insecure
+
+	authInfo := clientcmdapi.NewAuthInfo()
+	authInfo.Token = token
+
+	kubeContext := clientcmdapi.NewContext()
+	kubeContext.Cluster = "cluster"
+	kubeContext.AuthInfo = serviceAccount.Name
+	kubeContext.Namespace = serviceAccount.Namespace
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["cluster"] = cluster
+	config.AuthInfos[serviceAccount.Name] = authInfo
+	config.Contexts[serviceAccount.Name] = kubeContext
+	config.CurrentContext = serviceAccount.Name
+
+	b, err := clientcmd.Write(*config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write KubeConfig: %w", err)
+	}
+	return b, nil
+}
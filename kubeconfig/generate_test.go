@@ -0,0 +1,223 @@
+package kubeconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gitops-tools/pkg/secrets"
+	"github.com/google/go-cmp/cmp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var testServiceAccount = types.NamespacedName{Namespace: "tenant", Name: "deployer"}
+
+func TestKubeConfigGeneratorGenerate(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	cfg := &rest.Config{
+		Host:            "https://cluster.example.com",
+		TLSClientConfig: rest.TLSClientConfig{CAData: []byte("test-ca"), ServerName: "cluster.internal"},
+	}
+	g := newTestGenerator(t, cfg, fake.NewClientBuilder().Build(), func() time.Time { return now })
+
+	b, expiration, err := g.Generate(context.TODO(), testServiceAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !expiration.Equal(now.Add(time.Hour)) {
+		t.Fatalf("got expiration %s, want %s", expiration, now.Add(time.Hour))
+	}
+	config, err := clientcmd.Load(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kubeContext := config.Contexts[config.CurrentContext]
+	cluster := config.Clusters[kubeContext.Cluster]
+	want := []string{"https://cluster.example.com", "test-ca", "cluster.internal", "token-1", "tenant"}
+	got := []string{cluster.Server, string(cluster.CertificateAuthorityData), cluster.TLSServerName, config.AuthInfos[kubeContext.AuthInfo].Token, kubeContext.Namespace}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("failed to generate KubeConfig:\n%s", diff)
+	}
+}
+
+func TestKubeConfigGeneratorRefresh(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	cl := fake.NewClientBuilder().Build()
+	g := newTestGenerator(t, &rest.Config{Host: "https://cluster.example.com"}, cl, func() time.Time { return now })
+
+	refreshTests := []struct {
+		name        string
+		elapsed     time.Duration
+		wantToken   string
+		wantRequeue time.Duration
+	}{
+		{name: "missing secret", wantToken: "token-1", wantRequeue: time.Minute * 50},
+		{name: "valid token", elapsed: time.Minute * 30, wantToken: "token-1", wantRequeue: time.Minute * 20},
+		{name: "expiring token", elapsed: time.Minute * 25, wantToken: "token-2", wantRequeue: time.Minute * 50},
+	}
+
+	for _, tt := range refreshTests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.elapsed)
+
+			requeue, err := g.Refresh(context.TODO(), testServiceAccount, testSecretName)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if requeue != tt.wantRequeue {
+				t.Errorf("got requeue after %s, want %s", requeue, tt.wantRequeue)
+			}
+			cfg, err := restConfigFromSecret(context.TODO(), cl, testSecretName, ClientOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.BearerToken != tt.wantToken {
+				t.Errorf("got token %s, want %s", cfg.BearerToken, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestKubeConfigGeneratorRefreshWithShortenedLifetime(t *testing.T) {
+	refreshTests := []struct {
+		name        string
+		lifetime    time.Duration
+		wantRequeue time.Duration
+	}{
+		{name: "shorter than RefreshBefore", lifetime: time.Minute * 8, wantRequeue: time.Minute * 4},
+		{name: "shorter than the minimum interval", lifetime: time.Second * 40, wantRequeue: minRefreshInterval},
+		{name: "no expiration returned", wantRequeue: time.Minute * 50},
+	}
+
+	for _, tt := range refreshTests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+			cl := fake.NewClientBuilder().Build()
+			g := newTestGeneratorWithLifetime(t, &rest.Config{Host: "https://cluster.example.com"}, cl, func() time.Time { return now }, tt.lifetime)
+
+			requeue, err := g.Refresh(context.TODO(), testServiceAccount, testSecretName)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if requeue != tt.wantRequeue {
+				t.Errorf("got requeue after %s, want %s", requeue, tt.wantRequeue)
+			}
+		})
+	}
+}
+
+func TestKubeConfigGeneratorRefreshWithShortenedLifetimeTwice(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	cl := fake.NewClientBuilder().Build()
+	g := newTestGeneratorWithLifetime(t, &rest.Config{Host: "https://cluster.example.com"}, cl, func() time.Time { return now }, time.Minute*8)
+
+	for _, wantRequeue := range []time.Duration{time.Minute * 4, time.Minute * 3} {
+		requeue, err := g.Refresh(context.TODO(), testServiceAccount, testSecretName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if requeue != wantRequeue {
+			t.Errorf("got requeue after %s, want %s", requeue, wantRequeue)
+		}
+		now = now.Add(time.Minute)
+	}
+
+	cfg, err := restConfigFromSecret(context.TODO(), cl, testSecretName, ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BearerToken != "token-1" {
+		t.Errorf("got token %s, want token-1", cfg.BearerToken)
+	}
+}
+
+func TestKubeConfigGeneratorRefreshAnnotation(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	cl := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        testSecretName.Name,
+			Namespace:   testSecretName.Namespace,
+			Annotations: map[string]string{TokenExpirationAnnotation: "not a time"},
+		},
+		Data: map[string][]byte{"kubeconfig": []byte("test")},
+	}).Build()
+	g := newTestGenerator(t, &rest.Config{Host: "https://cluster.example.com"}, cl, func() time.Time { return now })
+
+	if _, err := g.Refresh(context.TODO(), testServiceAccount, testSecretName); err != nil {
+		t.Fatal(err)
+	}
+
+	var secret corev1.Secret
+	if err := cl.Get(context.TODO(), testSecretName, &secret); err != nil {
+		t.Fatal(err)
+	}
+	if v := secret.Annotations[TokenExpirationAnnotation]; v != "2026-10-18T13:00:00Z" {
+		t.Fatalf("got expiration annotation %q", v)
+	}
+	if v := secret.Annotations[TokenRefreshAnnotation]; v != "2026-10-18T12:50:00Z" {
+		t.Fatalf("got refresh annotation %q", v)
+	}
+}
+
+func TestKubeConfigGeneratorTokenError(t *testing.T) {
+	tokenClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		SubResourceCreate: func(ctx context.Context, client client.Client, subResourceName string, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+			return errors.New("forbidden")
+		},
+	}).Build()
+	g, err := NewKubeConfigGenerator(&rest.Config{Host: "https://cluster.example.com"}, fake.NewClientBuilder().Build(), nil, TokenClient(tokenClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = g.Generate(context.TODO(), testServiceAccount)
+	assertErrorMatch(t, `failed to request a token for ServiceAccount "tenant/deployer": forbidden`, err)
+}
+
+// newTestGenerator returns a generator that issues a new numbered token each
+// time a token is requested, that expires after the requested lifetime.
+func newTestGenerator(t *testing.T, cfg *rest.Config, cl client.Client, clock func() time.Time) *KubeConfigGenerator {
+	t.Helper()
+	return newTestGeneratorWithLifetime(t, cfg, cl, clock, -1)
+}
+
+// newTestGeneratorWithLifetime returns a generator that issues tokens that
+// expire after the lifetime, rather than the requested lifetime, or with no
+// expiration if the lifetime is zero.
+func newTestGeneratorWithLifetime(t *testing.T, cfg *rest.Config, cl client.Client, clock func() time.Time, lifetime time.Duration) *KubeConfigGenerator {
+	t.Helper()
+	issued := 0
+	tokenClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		SubResourceCreate: func(ctx context.Context, client client.Client, subResourceName string, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+			tokenRequest := subResource.(*authenticationv1.TokenRequest)
+			issued++
+			tokenRequest.Status.Token = fmt.Sprintf("token-%d", issued)
+			switch {
+			case lifetime < 0:
+				tokenRequest.Status.ExpirationTimestamp = metav1.NewTime(clock().Add(time.Duration(*tokenRequest.Spec.ExpirationSeconds) * time.Second))
+			case lifetime > 0:
+				tokenRequest.Status.ExpirationTimestamp = metav1.NewTime(clock().Add(lifetime))
+			}
+			return nil
+		},
+	}).Build()
+
+	g, err := NewKubeConfigGenerator(cfg, cl, secrets.NewKubeSecretSetter(cl), TokenClient(tokenClient), GeneratorClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
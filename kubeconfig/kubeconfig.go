@@ -2,15 +2,19 @@ package kubeconfig
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"time"
 
+	"github.com/gitops-tools/pkg/secrets"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
@@ -27,15 +31,50 @@ func init() {
 // secret parsing.
 type RESTOptions struct {
 	Impersonate *rest.ImpersonationConfig
+	// QPS and Burst override the client-side rate limits for the cluster.
+	QPS   float32
+	Burst int
+	// Timeout is the timeout for requests to the cluster.
+	Timeout time.Duration
+	// UserAgent is sent in requests to the cluster to identify the client.
+	UserAgent string
+	// ProxyURL is the URL of the proxy to connect to the cluster through.
+	ProxyURL *url.URL
+	// TLSServerName overrides the server name used to verify the certificate
+	// of the cluster.
+	TLSServerName string
+	// CAData overrides the PEM encoded CA certificates used to verify the
+	// certificate of the cluster.
+	CAData []byte
+	// CASecretRef is a secret with PEM encoded CA certificates in the ca.crt
+	// key, that override the CA certificates used to verify the certificate of
+	// the cluster.
+	// This is read with the same client as the KubeConfig secret, and can't be
+	// combined with CAData.
+	CASecretRef *types.NamespacedName
 }
 
 // ClientOptions can provide optional options when creating a client.
 type ClientOptions struct {
 	// REST is a set of RESTOptions.
 	REST RESTOptions
+	// Scheme is used by the parsed client to map Go types to resources.
+	// If this is not provided, the client-go scheme is used.
+	Scheme *runtime.Scheme
 	// Key is the key within the loaded secret to lookup the KubeConfig in.
 	// If this key does not exist, an error is returned.
 	Key string
+	// Format parses the rest.Config from the loaded secret.
+	// If this is not provided, and a Key is provided, the KubeConfig is read
+	// from the Key, otherwise the format is auto-detected.
+	Format SecretFormat
+	// Policy restricts the KubeConfig features that can be loaded from the
+	// secret, e.g. exec credential plugins and file references.
+	// The Policy is applied to the KubeConfigFormat and ClusterAPIFormat
+	// formats, including those in a provided Format, unless they have their
+	// own Policy.
+	// If this is not provided, all features are allowed.
+	Policy *LoadingPolicy
 }
 
 // ClientFromSecret loads a secret from the provided name, and returns a parsed
@@ -46,6 +85,10 @@ func ClientFromSecret(ctx context.Context, cl client.Client, name types.Namespac
 	if err != nil {
 		return nil, err
 	}
+	return clientForConfig(cfg, opts.Scheme)
+}
+
+func clientForConfig(cfg *rest.Config, scheme *runtime.Scheme) (client.Client, error) {
 	httpClient, err := rest.HTTPClientFor(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create an HTTP client for cluster: %w", err)
@@ -54,7 +97,7 @@ func ClientFromSecret(ctx context.Context, cl client.Client, name types.Namespac
 	if err != nil {
 		return nil, fmt.Errorf("failed to create REST mapper: %w", err)
 	}
-	parsedClient, err := client.New(cfg, client.Options{Mapper: mapper})
+	parsedClient, err := client.New(cfg, client.Options{Scheme: scheme, Mapper: mapper})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a client: %w", err)
 	}
@@ -63,22 +106,83 @@ func ClientFromSecret(ctx context.Context, cl client.Client, name types.Namespac
 }
 
 func restConfigFromSecret(ctx context.Context, cl client.Client, name types.NamespacedName, opts ClientOptions) (*rest.Config, error) {
+	secret, err := getSecret(ctx, cl, name)
+	if err != nil {
+		return nil, err
+	}
+	return restConfigForSecret(ctx, cl, secret, opts)
+}
+
+func getSecret(ctx context.Context, cl client.Client, name types.NamespacedName) (*corev1.Secret, error) {
 	var secret corev1.Secret
 	if err := cl.Get(ctx, name, &secret); err != nil {
 		return nil, fmt.Errorf("unable to read KubeConfig secret %q error: %w", name, err)
 	}
+	return &secret, nil
+}
 
-	b := secret.Data[opts.Key]
-	if len(b) == 0 {
-		return nil, fmt.Errorf("KubeConfig secret %q doesn't contain a KubeConfig, missing key %q", name, opts.Key)
-	}
-
-	cfg, err := clientcmd.RESTConfigFromKubeConfig(b)
+func restConfigForSecret(ctx context.Context, cl client.Client, secret *corev1.Secret, opts ClientOptions) (*rest.Config, error) {
+	cfg, err := secretFormat(opts).RESTConfig(secret)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse REST Config from secret data: %w", err)
+		return nil, err
 	}
-	if opts.REST.Impersonate != nil {
-		cfg.Impersonate = *opts.REST.Impersonate
+	if err := applyRESTOptions(ctx, cl, cfg, opts.REST); err != nil {
+		return nil, err
 	}
 	return cfg, nil
 }
+
+func applyRESTOptions(ctx context.Context, cl client.Client, cfg *rest.Config, opts RESTOptions) error {
+	if opts.Impersonate != nil {
+		cfg.Impersonate = *opts.Impersonate
+	}
+	if opts.QPS > 0 {
+		cfg.QPS = opts.QPS
+	}
+	if opts.Burst > 0 {
+		cfg.Burst = opts.Burst
+	}
+	if opts.Timeout > 0 {
+		cfg.Timeout = opts.Timeout
+	}
+	if opts.UserAgent != "" {
+		cfg.UserAgent = opts.UserAgent
+	}
+	if opts.ProxyURL != nil {
+		cfg.Proxy = http.ProxyURL(opts.ProxyURL)
+	}
+	if opts.TLSServerName != "" {
+		cfg.ServerName = opts.TLSServerName
+	}
+
+	caData := opts.CAData
+	if opts.CASecretRef != nil {
+		if len(caData) > 0 {
+			return errors.New("CAData and CASecretRef can't both be provided")
+		}
+		var caSecret corev1.Secret
+		if err := cl.Get(ctx, *opts.CASecretRef, &caSecret); err != nil {
+			return fmt.Errorf("unable to read CA secret %q error: %w", *opts.CASecretRef, err)
+		}
+		bundle, err := secrets.ParseCABundle(&caSecret)
+		if err != nil {
+			return err
+		}
+		caData = bundle.PEM
+	}
+	if len(caData) > 0 {
+		cfg.CAData = caData
+		cfg.CAFile = ""
+	}
+	return nil
+}
+
+func secretFormat(opts ClientOptions) SecretFormat {
+	if opts.Format != nil {
+		return withPolicy(opts.Format, opts.Policy)
+	}
+	if opts.Key != "" {
+		return KubeConfigFormat{Key: opts.Key, Policy: opts.Policy}
+	}
+	return AutoDetectFormat(ClusterAPIFormat{Policy: opts.Policy}, ArgoCDFormat{}, KubeConfigFormat{Key: DefaultKubeConfigKey, Policy: opts.Policy})
+}
@@ -2,18 +2,30 @@ package kubeconfig
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/gitops-tools/pkg/test"
+	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 )
 
@@ -106,6 +118,96 @@ func TestClientFromSecret(t *testing.T) {
 	})
 }
 
+func TestRESTOptions(t *testing.T) {
+	caPEM := generateCACert(t)
+	caSecretName := types.NamespacedName{Namespace: "default", Name: "test-ca"}
+	kubeConfig := generateKubeConfig(t, "default", &rest.Config{Host: "https://cluster.example.com", BearerToken: "test-token"})
+	cl := fake.NewClientBuilder().WithObjects(
+		newTestSecret(nil, map[string][]byte{"kubeconfig": kubeConfig}),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: caSecretName.Namespace, Name: caSecretName.Name},
+			Data:       map[string][]byte{"ca.crt": []byte(caPEM)},
+		},
+	).Build()
+	proxyURL, err := url.Parse("http://proxy.example.com:3128")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := restConfigFromSecret(context.TODO(), cl, testSecretName, ClientOptions{
+		REST: RESTOptions{
+			QPS:           50,
+			Burst:         100,
+			Timeout:       time.Second * 30,
+			UserAgent:     "test-controller/v1",
+			ProxyURL:      proxyURL,
+			TLSServerName: "cluster.internal",
+			CASecretRef:   &caSecretName,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []any{float32(50), 100, time.Second * 30, "test-controller/v1", "cluster.internal", caPEM}
+	got := []any{cfg.QPS, cfg.Burst, cfg.Timeout, cfg.UserAgent, cfg.ServerName, string(cfg.CAData)}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("failed to apply REST options:\n%s", diff)
+	}
+	proxy, err := cfg.Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "cluster.example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proxy.String() != proxyURL.String() {
+		t.Fatalf("got proxy %s, want %s", proxy, proxyURL)
+	}
+}
+
+func TestRESTOptionsErrors(t *testing.T) {
+	caSecretName := types.NamespacedName{Namespace: "default", Name: "test-ca"}
+	kubeConfig := generateKubeConfig(t, "default", &rest.Config{Host: "https://cluster.example.com", BearerToken: "test-token"})
+	cl := fake.NewClientBuilder().WithObjects(newTestSecret(nil, map[string][]byte{"kubeconfig": kubeConfig})).Build()
+
+	errorTests := []struct {
+		name    string
+		opts    RESTOptions
+		wantErr string
+	}{
+		{
+			name:    "missing CA secret",
+			opts:    RESTOptions{CASecretRef: &caSecretName},
+			wantErr: `unable to read CA secret "default/test-ca" error: .*not found`,
+		},
+		{
+			name:    "CA data and secret",
+			opts:    RESTOptions{CAData: []byte("test"), CASecretRef: &caSecretName},
+			wantErr: "CAData and CASecretRef can't both be provided",
+		},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := restConfigFromSecret(context.TODO(), cl, testSecretName, ClientOptions{REST: tt.opts})
+			assertErrorMatch(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestClientFromSecretWithScheme(t *testing.T) {
+	kubeConfig := generateKubeConfig(t, "default", &rest.Config{Host: "https://cluster.example.com", BearerToken: "test-token"})
+	cl := fake.NewClientBuilder().WithObjects(newTestSecret(nil, map[string][]byte{"kubeconfig": kubeConfig})).Build()
+	scheme := runtime.NewScheme()
+
+	parsedClient, err := ClientFromSecret(context.TODO(), cl, testSecretName, ClientOptions{Scheme: scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsedClient.Scheme() != scheme {
+		t.Fatal("parsed client does not use the provided scheme")
+	}
+}
+
 func assertErrorMatch(t *testing.T, s string, e error) {
 	if !test.MatchError(t, s, e) {
 		t.Fatalf("failed to match error %s against %s", e, s)
@@ -176,6 +278,28 @@ func assertSecretReadable(t *testing.T, cl client.Client, name types.NamespacedN
 	}
 }
 
+func generateCACert(t *testing.T) string {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
 func generateKubeConfig(t *testing.T, name string, rc *rest.Config) []byte {
 	t.Helper()
 	cluster := clientcmdapi.NewCluster()
@@ -0,0 +1,143 @@
+package kubeconfig
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// LoadingPolicy restricts the KubeConfig features that can execute binaries
+// or read files on the host that loads the KubeConfig.
+//
+// The zero value rejects all exec credential plugins, auth-provider plugins
+// and file references.
+type LoadingPolicy struct {
+	// AllowedExecCommands are the exec credential plugin commands that can be
+	// used.
+	AllowedExecCommands []string
+	// AllowedAuthProviders are the names of the auth-provider plugins that can
+	// be used.
+	AllowedAuthProviders []string
+	// AllowedPaths are the files that can be referenced by the
+	// certificate-authority, client-certificate, client-key and tokenFile
+	// fields.
+	AllowedPaths []string
+	// Context is the only context that is loaded from the KubeConfig.
+	// If this is not provided, the current-context is loaded.
+	Context string
+}
+
+// restConfigFromKubeConfig parses a KubeConfig, if a policy is provided, the
+// context to load is checked against the policy before any files are read.
+func restConfigFromKubeConfig(b []byte, policy *LoadingPolicy) (*rest.Config, error) {
+	if policy == nil {
+		return clientcmd.RESTConfigFromKubeConfig(b)
+	}
+
+	config, err := clientcmd.Load(b)
+	if err != nil {
+		return nil, err
+	}
+	contextName := config.CurrentContext
+	if policy.Context != "" {
+		contextName = policy.Context
+	}
+	if err := policy.check(config, contextName); err != nil {
+		return nil, &policyError{err: err}
+	}
+
+	return clientcmd.NewNonInteractiveClientConfig(*config, contextName, &clientcmd.ConfigOverrides{CurrentContext: contextName}, nil).ClientConfig()
+}
+
+// withPolicy returns the format with the policy applied to the formats that
+// load KubeConfigs and don't have their own policy.
+func withPolicy(format SecretFormat, policy *LoadingPolicy) SecretFormat {
+	if policy == nil {
+		return format
+	}
+	switch f := format.(type) {
+	case KubeConfigFormat:
+		if f.Policy == nil {
+			f.Policy = policy
+		}
+		return f
+	case *KubeConfigFormat:
+		return withPolicy(*f, policy)
+	case ClusterAPIFormat:
+		if f.Policy == nil {
+			f.Policy = policy
+		}
+		return f
+	case *ClusterAPIFormat:
+		return withPolicy(*f, policy)
+	case autoDetectFormat:
+		formats := make([]SecretFormat, len(f.formats))
+		for i := range f.formats {
+			formats[i] = withPolicy(f.formats[i], policy)
+		}
+		return autoDetectFormat{formats: formats}
+	}
+	return format
+}
+
+// check returns an error naming each field in the context that is not
+// allowed by the policy.
+func (p *LoadingPolicy) check(config *clientcmdapi.Config, contextName string) error {
+	if contextName == "" {
+		return errors.New("current-context is not set")
+	}
+	kubeContext, ok := config.Contexts[contextName]
+	if !ok {
+		return fmt.Errorf("contexts[%q] does not exist", contextName)
+	}
+
+	var errs []error
+	if cluster, ok := config.Clusters[kubeContext.Cluster]; ok {
+		errs = append(errs, p.checkPath(fmt.Sprintf("clusters[%q].certificate-authority", kubeContext.Cluster), cluster.CertificateAuthority))
+	}
+	if authInfo, ok := config.AuthInfos[kubeContext.AuthInfo]; ok {
+		field := fmt.Sprintf("users[%q]", kubeContext.AuthInfo)
+		errs = append(errs,
+			p.checkPath(field+".client-certificate", authInfo.ClientCertificate),
+			p.checkPath(field+".client-key", authInfo.ClientKey),
+			p.checkPath(field+".tokenFile", authInfo.TokenFile))
+		if authInfo.Exec != nil && !slices.Contains(p.AllowedExecCommands, authInfo.Exec.Command) {
+			errs = append(errs, fmt.Errorf("%s.exec.command %q is not an allowed exec command", field, authInfo.Exec.Command))
+		}
+		if authInfo.AuthProvider != nil && !slices.Contains(p.AllowedAuthProviders, authInfo.AuthProvider.Name) {
+			errs = append(errs, fmt.Errorf("%s.auth-provider.name %q is not an allowed auth-provider", field, authInfo.AuthProvider.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *LoadingPolicy) checkPath(field, path string) error {
+	if path == "" {
+		return nil
+	}
+	for _, allowed := range p.AllowedPaths {
+		if filepath.Clean(allowed) == filepath.Clean(path) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %q is not an allowed path", field, path)
+}
+
+// policyError is returned when a KubeConfig is rejected by the LoadingPolicy,
+// to distinguish it from parsing errors.
+type policyError struct {
+	err error
+}
+
+func (e *policyError) Error() string {
+	return e.err.Error()
+}
+
+func (e *policyError) Unwrap() error {
+	return e.err
+}
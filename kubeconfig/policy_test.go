@@ -0,0 +1,170 @@
+package kubeconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLoadingPolicy(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	policyTests := []struct {
+		name     string
+		policy   *LoadingPolicy
+		authInfo *clientcmdapi.AuthInfo
+		wantHost string
+		wantErr  string
+	}{
+		{
+			name:     "no policy allows exec",
+			authInfo: &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "aws", APIVersion: "client.authentication.k8s.io/v1", InteractiveMode: clientcmdapi.NeverExecInteractiveMode}},
+			wantHost: "https://cluster.example.com",
+		},
+		{
+			name:     "exec rejected",
+			policy:   &LoadingPolicy{},
+			authInfo: &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "aws", APIVersion: "client.authentication.k8s.io/v1", InteractiveMode: clientcmdapi.NeverExecInteractiveMode}},
+			wantErr:  `KubeConfig secret "default/test-kubeconfig" is not allowed by the loading policy: users\["default"\].exec.command "aws" is not an allowed exec command`,
+		},
+		{
+			name:     "allowed exec command",
+			policy:   &LoadingPolicy{AllowedExecCommands: []string{"aws"}},
+			authInfo: &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "aws", APIVersion: "client.authentication.k8s.io/v1", InteractiveMode: clientcmdapi.NeverExecInteractiveMode}},
+			wantHost: "https://cluster.example.com",
+		},
+		{
+			name:     "auth-provider rejected",
+			policy:   &LoadingPolicy{AllowedAuthProviders: []string{"oidc"}},
+			authInfo: &clientcmdapi.AuthInfo{AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "gcp"}},
+			wantErr:  `users\["default"\].auth-provider.name "gcp" is not an allowed auth-provider`,
+		},
+		{
+			name:     "file references rejected",
+			policy:   &LoadingPolicy{},
+			authInfo: &clientcmdapi.AuthInfo{TokenFile: "/etc/shadow", ClientCertificate: "/etc/client.crt"},
+			wantErr:  `users\["default"\].client-certificate "/etc/client.crt" is not an allowed path\nusers\["default"\].tokenFile "/etc/shadow" is not an allowed path`,
+		},
+		{
+			name:     "allowed file reference",
+			policy:   &LoadingPolicy{AllowedPaths: []string{tokenFile}},
+			authInfo: &clientcmdapi.AuthInfo{TokenFile: tokenFile},
+			wantHost: "https://cluster.example.com",
+		},
+	}
+
+	for _, tt := range policyTests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := writeKubeConfig(t, "default", map[string]*clientcmdapi.AuthInfo{"default": tt.authInfo})
+			format := KubeConfigFormat{Key: "kubeconfig", Policy: tt.policy}
+
+			cfg, err := format.RESTConfig(newTestSecret(nil, map[string][]byte{"kubeconfig": b}))
+			if tt.wantErr != "" {
+				assertErrorMatch(t, tt.wantErr, err)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Host != tt.wantHost {
+				t.Fatalf("got host %s, want %s", cfg.Host, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestLoadingPolicyContext(t *testing.T) {
+	b := writeKubeConfig(t, "admin", map[string]*clientcmdapi.AuthInfo{
+		"admin":  {Exec: &clientcmdapi.ExecConfig{Command: "/bin/sh", APIVersion: "client.authentication.k8s.io/v1", InteractiveMode: clientcmdapi.NeverExecInteractiveMode}},
+		"tenant": {Token: "tenant-token"},
+	})
+	secret := newTestSecret(nil, map[string][]byte{"kubeconfig": b})
+
+	t.Run("current-context", func(t *testing.T) {
+		_, err := KubeConfigFormat{Key: "kubeconfig", Policy: &LoadingPolicy{}}.RESTConfig(secret)
+		assertErrorMatch(t, `users\["admin"\].exec.command "/bin/sh" is not an allowed exec command`, err)
+	})
+
+	t.Run("pinned context", func(t *testing.T) {
+		cfg, err := KubeConfigFormat{Key: "kubeconfig", Policy: &LoadingPolicy{Context: "tenant"}}.RESTConfig(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.BearerToken != "tenant-token" || cfg.ExecProvider != nil {
+			t.Fatalf("got token %q and exec %v, want the tenant context", cfg.BearerToken, cfg.ExecProvider)
+		}
+	})
+
+	t.Run("missing pinned context", func(t *testing.T) {
+		_, err := KubeConfigFormat{Key: "kubeconfig", Policy: &LoadingPolicy{Context: "unknown"}}.RESTConfig(secret)
+		assertErrorMatch(t, `contexts\["unknown"\] does not exist`, err)
+	})
+}
+
+func TestRESTConfigFromSecretWithPolicy(t *testing.T) {
+	b := writeKubeConfig(t, "default", map[string]*clientcmdapi.AuthInfo{
+		"default": {Exec: &clientcmdapi.ExecConfig{Command: "aws", APIVersion: "client.authentication.k8s.io/v1", InteractiveMode: clientcmdapi.NeverExecInteractiveMode}},
+	})
+	cl := fake.NewClientBuilder().WithObjects(newTestSecret(map[string]string{"cluster.x-k8s.io/cluster-name": "test"}, map[string][]byte{"value": b})).Build()
+
+	_, err := restConfigFromSecret(context.TODO(), cl, testSecretName, ClientOptions{Policy: &LoadingPolicy{}})
+	assertErrorMatch(t, `not allowed by the loading policy: users\["default"\].exec.command "aws"`, err)
+}
+
+func TestRESTConfigFromSecretWithPolicyAndFormat(t *testing.T) {
+	b := writeKubeConfig(t, "default", map[string]*clientcmdapi.AuthInfo{
+		"default": {Exec: &clientcmdapi.ExecConfig{Command: "aws", APIVersion: "client.authentication.k8s.io/v1", InteractiveMode: clientcmdapi.NeverExecInteractiveMode}},
+	})
+	cl := fake.NewClientBuilder().WithObjects(newTestSecret(nil, map[string][]byte{"kubeconfig": b, "value": b})).Build()
+
+	formatTests := []struct {
+		name   string
+		format SecretFormat
+	}{
+		{name: "KubeConfig format", format: KubeConfigFormat{Key: "kubeconfig"}},
+		{name: "KubeConfig format pointer", format: &KubeConfigFormat{Key: "kubeconfig"}},
+		{name: "Cluster API format", format: ClusterAPIFormat{}},
+		{name: "auto-detected formats", format: AutoDetectFormat()},
+	}
+
+	for _, tt := range formatTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := restConfigFromSecret(context.TODO(), cl, testSecretName, ClientOptions{Format: tt.format, Policy: &LoadingPolicy{}})
+			assertErrorMatch(t, `not allowed by the loading policy: users\["default"\].exec.command "aws"`, err)
+		})
+	}
+
+	t.Run("format with a policy", func(t *testing.T) {
+		format := KubeConfigFormat{Key: "kubeconfig", Policy: &LoadingPolicy{AllowedExecCommands: []string{"aws"}}}
+		if _, err := restConfigFromSecret(context.TODO(), cl, testSecretName, ClientOptions{Format: format, Policy: &LoadingPolicy{}}); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// writeKubeConfig writes a KubeConfig with a context for each user, all using
+// the same cluster.
+func writeKubeConfig(t *testing.T, currentContext string, authInfos map[string]*clientcmdapi.AuthInfo) []byte {
+	t.Helper()
+	config := clientcmdapi.NewConfig()
+	config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "https://cluster.example.com"}
+	for name, authInfo := range authInfos {
+		config.AuthInfos[name] = authInfo
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: name}
+	}
+	config.CurrentContext = currentContext
+
+	b, err := clientcmd.Write(*config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,190 @@
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ClusterRegistry caches the clients parsed from KubeConfig secrets.
+//
+// Clients are keyed by the name and resourceVersion of the secret, and the
+// resourceVersion of the CASecretRef secret if one is provided, and are rebuilt
+// when either secret changes.
+type ClusterRegistry struct {
+	kubeClient client.Client
+	opts       ClientOptions
+	newClient  func(*rest.Config, *runtime.Scheme) (client.Client, error)
+	builds     singleflight.Group
+
+	mu         sync.Mutex
+	clients    map[types.NamespacedName]cachedClient
+	discovered map[types.NamespacedName]bool
+}
+
+type cachedClient struct {
+	version string
+	client  client.Client
+}
+
+// NewClusterRegistry creates and returns a ClusterRegistry that reads
+// KubeConfig secrets with the client, and parses them with the options.
+//
+// The client is read from for every call to Client, and so a client that reads
+// from a cache e.g. the client from a controller-runtime Manager, is
+// recommended.
+func NewClusterRegistry(cl client.Client, opts ClientOptions) *ClusterRegistry {
+	return &ClusterRegistry{
+		kubeClient: cl,
+		opts:       opts,
+		newClient:  clientForConfig,
+		clients:    map[types.NamespacedName]cachedClient{},
+		discovered: map[types.NamespacedName]bool{},
+	}
+}
+
+// Client returns a client for the cluster in the named secret.
+//
+// The client is reused until the resourceVersion of the secret or the
+// CASecretRef secret changes, and is evicted if the secret no longer exists.
+//
+// Concurrent calls for the same version of the secrets share a single client.
+func (r *ClusterRegistry) Client(ctx context.Context, name types.NamespacedName) (client.Client, error) {
+	secret, err := getSecret(ctx, r.kubeClient, name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Evict(name)
+		}
+		return nil, err
+	}
+	version, err := r.clientVersion(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	cached, ok := r.clients[name]
+	r.mu.Unlock()
+	if ok && cached.version == version {
+		return cached.client, nil
+	}
+
+	v, err, _ := r.builds.Do(name.String()+"@"+version, func() (any, error) {
+		cfg, err := restConfigForSecret(ctx, r.kubeClient, secret, r.opts)
+		if err != nil {
+			return nil, err
+		}
+		cl, err := r.newClient(cfg, r.opts.Scheme)
+		if err != nil {
+			return nil, err
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.clients[name] = cachedClient{version: version, client: cl}
+		return cl, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(client.Client), nil
+}
+
+// clientVersion returns the resourceVersion of the secret, combined with the
+// resourceVersion of the CASecretRef secret if one is provided.
+func (r *ClusterRegistry) clientVersion(ctx context.Context, secret *corev1.Secret) (string, error) {
+	ref := r.opts.REST.CASecretRef
+	if ref == nil {
+		return secret.ResourceVersion, nil
+	}
+	var caSecret corev1.Secret
+	if err := r.kubeClient.Get(ctx, *ref, &caSecret); err != nil {
+		return "", fmt.Errorf("unable to read CA secret %q error: %w", *ref, err)
+	}
+	return secret.ResourceVersion + "/" + caSecret.ResourceVersion, nil
+}
+
+// Evict removes the cached client for the named secret.
+func (r *ClusterRegistry) Evict(name types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.clients, name)
+	delete(r.discovered, name)
+}
+
+// Clusters returns the names of the secrets that have been discovered by
+// watching, sorted by namespace and name.
+func (r *ClusterRegistry) Clusters() []types.NamespacedName {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]types.NamespacedName, 0, len(r.discovered))
+	for name := range r.discovered {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].String() < names[j].String() })
+	return names
+}
+
+// Watch registers an event handler with a Secret informer that discovers the
+// secrets that match the selector, evicts the cached client when a secret
+// changes, and removes the secret when it is deleted, or no longer matches the
+// selector.
+//
+// Clients for the discovered secrets are created when they are first
+// requested.
+//
+//	informer, err := mgr.GetCache().GetInformer(ctx, &corev1.Secret{})
+func (r *ClusterRegistry) Watch(informer cache.Informer, selector labels.Selector) error {
+	_, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if secret, ok := obj.(*corev1.Secret); ok && selector.Matches(labels.Set(secret.Labels)) {
+				r.discover(client.ObjectKeyFromObject(secret))
+			}
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			oldSecret, ok := oldObj.(*corev1.Secret)
+			if !ok {
+				return
+			}
+			newSecret, ok := newObj.(*corev1.Secret)
+			if !ok || oldSecret.ResourceVersion == newSecret.ResourceVersion {
+				return
+			}
+			name := client.ObjectKeyFromObject(newSecret)
+			r.Evict(name)
+			if selector.Matches(labels.Set(newSecret.Labels)) {
+				r.discover(name)
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if secret, ok := obj.(*corev1.Secret); ok {
+				r.Evict(client.ObjectKeyFromObject(secret))
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add cluster registry event handler: %w", err)
+	}
+	return nil
+}
+
+func (r *ClusterRegistry) discover(name types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.discovered[name] = true
+}
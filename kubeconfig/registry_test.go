@@ -0,0 +1,240 @@
+package kubeconfig
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+func TestClusterRegistryClient(t *testing.T) {
+	kubeConfig := generateKubeConfig(t, "default", &rest.Config{Host: "https://cluster.example.com", BearerToken: "test-token"})
+	cl := fake.NewClientBuilder().WithObjects(newTestSecret(nil, map[string][]byte{"kubeconfig": kubeConfig})).Build()
+	r := NewClusterRegistry(cl, ClientOptions{})
+
+	first := assertRegistryClient(t, r, testSecretName)
+	if second := assertRegistryClient(t, r, testSecretName); second != first {
+		t.Fatal("client was not reused for an unchanged secret")
+	}
+
+	var secret corev1.Secret
+	if err := cl.Get(context.TODO(), testSecretName, &secret); err != nil {
+		t.Fatal(err)
+	}
+	secret.Data["kubeconfig"] = generateKubeConfig(t, "default", &rest.Config{Host: "https://cluster.example.com", BearerToken: "new-token"})
+	if err := cl.Update(context.TODO(), &secret); err != nil {
+		t.Fatal(err)
+	}
+	updated := assertRegistryClient(t, r, testSecretName)
+	if updated == first {
+		t.Fatal("client was not rebuilt for an updated secret")
+	}
+
+	r.Evict(testSecretName)
+	if evicted := assertRegistryClient(t, r, testSecretName); evicted == updated {
+		t.Fatal("client was not rebuilt after eviction")
+	}
+}
+
+func TestClusterRegistryClientWithRotatedCA(t *testing.T) {
+	caSecretName := types.NamespacedName{Namespace: "default", Name: "test-ca"}
+	kubeConfig := generateKubeConfig(t, "default", &rest.Config{Host: "https://cluster.example.com", BearerToken: "test-token"})
+	cl := fake.NewClientBuilder().WithObjects(
+		newTestSecret(nil, map[string][]byte{"kubeconfig": kubeConfig}),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: caSecretName.Namespace, Name: caSecretName.Name},
+			Data:       map[string][]byte{"ca.crt": []byte(generateCACert(t))},
+		},
+	).Build()
+	r := NewClusterRegistry(cl, ClientOptions{REST: RESTOptions{CASecretRef: &caSecretName}})
+
+	first := assertRegistryClient(t, r, testSecretName)
+	if second := assertRegistryClient(t, r, testSecretName); second != first {
+		t.Fatal("client was not reused for an unchanged CA secret")
+	}
+
+	var caSecret corev1.Secret
+	if err := cl.Get(context.TODO(), caSecretName, &caSecret); err != nil {
+		t.Fatal(err)
+	}
+	caSecret.Data["ca.crt"] = []byte(generateCACert(t))
+	if err := cl.Update(context.TODO(), &caSecret); err != nil {
+		t.Fatal(err)
+	}
+	if rotated := assertRegistryClient(t, r, testSecretName); rotated == first {
+		t.Fatal("client was not rebuilt for a rotated CA")
+	}
+}
+
+func TestClusterRegistryClientConcurrently(t *testing.T) {
+	kubeConfig := generateKubeConfig(t, "default", &rest.Config{Host: "https://cluster.example.com", BearerToken: "test-token"})
+	var gets atomic.Int32
+	cl := fake.NewClientBuilder().WithObjects(newTestSecret(nil, map[string][]byte{"kubeconfig": kubeConfig})).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, client client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				gets.Add(1)
+				return client.Get(ctx, key, obj, opts...)
+			},
+		}).Build()
+	r := NewClusterRegistry(cl, ClientOptions{})
+	var builds atomic.Int32
+	release := make(chan struct{})
+	r.newClient = func(cfg *rest.Config, scheme *runtime.Scheme) (client.Client, error) {
+		builds.Add(1)
+		<-release
+		return clientForConfig(cfg, scheme)
+	}
+
+	const callers = 5
+	clients := make([]client.Client, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Go(func() {
+			clients[i] = assertRegistryClient(t, r, testSecretName)
+		})
+	}
+	// Wait for every caller to read the secret before the client is built.
+	for gets.Load() < callers {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+	wg.Wait()
+
+	if n := builds.Load(); n != 1 {
+		t.Fatalf("got %d clients built, want 1", n)
+	}
+	for _, c := range clients[1:] {
+		if c != clients[0] {
+			t.Fatal("concurrent callers got different clients")
+		}
+	}
+}
+
+func TestClusterRegistryClientWithDeletedSecret(t *testing.T) {
+	kubeConfig := generateKubeConfig(t, "default", &rest.Config{Host: "https://cluster.example.com", BearerToken: "test-token"})
+	secret := newTestSecret(nil, map[string][]byte{"kubeconfig": kubeConfig})
+	cl := fake.NewClientBuilder().WithObjects(secret).Build()
+	r := NewClusterRegistry(cl, ClientOptions{})
+	assertRegistryClient(t, r, testSecretName)
+	r.discover(testSecretName)
+
+	if err := cl.Delete(context.TODO(), secret); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := r.Client(context.TODO(), testSecretName)
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("got %s, want not found", err)
+	}
+	if _, ok := r.clients[testSecretName]; ok {
+		t.Fatal("client was not evicted for a deleted secret")
+	}
+	assertClusters(t, r, []types.NamespacedName{})
+}
+
+func TestClusterRegistryClientErrors(t *testing.T) {
+	cl := fake.NewClientBuilder().WithObjects(newTestSecret(nil, map[string][]byte{"token": []byte("test")})).Build()
+	r := NewClusterRegistry(cl, ClientOptions{})
+
+	_, err := r.Client(context.TODO(), types.NamespacedName{Namespace: "default", Name: "unknown"})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("got %s, want not found", err)
+	}
+
+	_, err = r.Client(context.TODO(), testSecretName)
+	assertErrorMatch(t, `KubeConfig secret "default/test-kubeconfig" is not in a known format`, err)
+}
+
+func TestClusterRegistryWatch(t *testing.T) {
+	kubeConfig := generateKubeConfig(t, "default", &rest.Config{Host: "https://cluster.example.com", BearerToken: "test-token"})
+	secret := newTestSecret(map[string]string{"gitops-tools/cluster": "true"}, map[string][]byte{"kubeconfig": kubeConfig})
+	secret.ResourceVersion = "1"
+	unlabelled := newTestSecret(nil, map[string][]byte{"kubeconfig": kubeConfig})
+	unlabelled.Name = "unlabelled"
+	cl := fake.NewClientBuilder().WithObjects(secret.DeepCopy()).Build()
+	r := NewClusterRegistry(cl, ClientOptions{})
+	informer := &controllertest.FakeInformer{}
+	if err := r.Watch(informer, labels.SelectorFromSet(labels.Set{"gitops-tools/cluster": "true"})); err != nil {
+		t.Fatal(err)
+	}
+
+	informer.Add(secret)
+	informer.Add(unlabelled)
+	assertClusters(t, r, []types.NamespacedName{testSecretName})
+
+	first := assertRegistryClient(t, r, testSecretName)
+	informer.Update(secret, secret.DeepCopy())
+	if unchanged := assertRegistryClient(t, r, testSecretName); unchanged != first {
+		t.Fatal("client was evicted for an unchanged secret")
+	}
+
+	updated := secret.DeepCopy()
+	updated.ResourceVersion = "2"
+	updated.Labels = nil
+	informer.Update(secret, updated)
+	assertClusters(t, r, []types.NamespacedName{})
+
+	informer.Update(updated, secret)
+	assertClusters(t, r, []types.NamespacedName{testSecretName})
+
+	informer.Delete(secret)
+	assertClusters(t, r, []types.NamespacedName{})
+}
+
+func TestClusterRegistryWithEnvTest(t *testing.T) {
+	testEnv := &envtest.Environment{}
+	testCfg, err := testEnv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := testEnv.Stop(); err != nil {
+			t.Fatalf("failed to shutdown testEnv: %s", err)
+		}
+	}()
+
+	cl, err := client.New(testCfg, client.Options{Scheme: runtimeScheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testWriteSecret(t, generateKubeConfig(t, "default", testCfg), cl, testSecretName)
+	r := NewClusterRegistry(cl, ClientOptions{Key: "kube.config"})
+
+	parsedClient := assertRegistryClient(t, r, testSecretName)
+	assertSecretReadable(t, parsedClient, testSecretName)
+	if cached := assertRegistryClient(t, r, testSecretName); cached != parsedClient {
+		t.Fatal("client was not reused for an unchanged secret")
+	}
+}
+
+func assertRegistryClient(t *testing.T, r *ClusterRegistry, name types.NamespacedName) client.Client {
+	t.Helper()
+	cl, err := r.Client(context.TODO(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cl
+}
+
+func assertClusters(t *testing.T, r *ClusterRegistry, want []types.NamespacedName) {
+	t.Helper()
+	if diff := cmp.Diff(want, r.Clusters()); diff != "" {
+		t.Fatalf("failed to discover clusters:\n%s", diff)
+	}
+}
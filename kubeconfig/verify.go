@@ -0,0 +1,138 @@
+package kubeconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AccessCheck is a permission that the credentials in a KubeConfig secret
+// should have in the cluster.
+type AccessCheck struct {
+	Verb     string
+	Group    string
+	Resource string
+	// Namespace is the namespace to check, if empty, the check is for all
+	// namespaces, or for a cluster-scoped resource.
+	Namespace string
+	// Name is the name of the resource to check, if empty, the check is for
+	// all resources.
+	Name string
+}
+
+// String returns a description of the check for reports.
+func (c AccessCheck) String() string {
+	resource := c.Resource
+	if c.Group != "" {
+		resource = c.Resource + "." + c.Group
+	}
+	if c.Name != "" {
+		resource = resource + "/" + c.Name
+	}
+	if c.Namespace != "" {
+		return fmt.Sprintf("%s %s in namespace %s", c.Verb, resource, c.Namespace)
+	}
+	return fmt.Sprintf("%s %s", c.Verb, resource)
+}
+
+// AccessResult is the result of an AccessCheck.
+type AccessResult struct {
+	Check   AccessCheck
+	Allowed bool
+	// Reason is the reason provided by the authorizer, if any.
+	Reason string
+}
+
+// VerifyReport is the result of verifying a KubeConfig secret.
+type VerifyReport struct {
+	// ServerVersion is the version reported by the cluster.
+	ServerVersion *version.Info
+	// Access has a result for each AccessCheck in the order they were
+	// provided.
+	Access []AccessResult
+}
+
+// Allowed returns true if all the access checks were allowed.
+func (r VerifyReport) Allowed() bool {
+	return len(r.Denied()) == 0
+}
+
+// Denied returns the checks that were not allowed.
+func (r VerifyReport) Denied() []AccessCheck {
+	var denied []AccessCheck
+	for _, result := range r.Access {
+		if !result.Allowed {
+			denied = append(denied, result.Check)
+		}
+	}
+	return denied
+}
+
+// Verify loads a secret from the provided name, and checks that the cluster
+// in the parsed rest.Config is reachable, and runs a SelfSubjectAccessReview
+// for each of the checks.
+//
+// An error is returned if the cluster can't be reached or the reviews can't
+// be created, checks that are denied are reported in the VerifyReport.
+func Verify(ctx context.Context, cl client.Client, name types.NamespacedName, opts ClientOptions, checks ...AccessCheck) (*VerifyReport, error) {
+	cfg, err := restConfigFromSecret(ctx, cl, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a clientset: %w", err)
+	}
+
+	report := &VerifyReport{}
+	report.ServerVersion, err = serverVersion(ctx, clientset)
+	if err != nil {
+		return nil, fmt.Errorf("cluster in KubeConfig secret %q is not reachable: %w", name, err)
+	}
+
+	for _, check := range checks {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Verb:      check.Verb,
+					Group:     check.Group,
+					Resource:  check.Resource,
+					Namespace: check.Namespace,
+					Name:      check.Name,
+				},
+			},
+		}
+		review, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to check access to %s: %w", check, err)
+		}
+		report.Access = append(report.Access, AccessResult{
+			Check:   check,
+			Allowed: review.Status.Allowed && !review.Status.Denied,
+			Reason:  review.Status.Reason,
+		})
+	}
+
+	return report, nil
+}
+
+// serverVersion is equivalent to the discovery client ServerVersion, but uses
+// the context.
+func serverVersion(ctx context.Context, clientset kubernetes.Interface) (*version.Info, error) {
+	b, err := clientset.Discovery().RESTClient().Get().AbsPath("/version").DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var info version.Info
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse server version: %w", err)
+	}
+	return &info, nil
+}
@@ -0,0 +1,128 @@
+package kubeconfig
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+func TestVerify(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/version":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"major":"1","minor":"36","gitVersion":"v1.36.2"}`))
+		case "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews":
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var review authorizationv1.SelfSubjectAccessReview
+			if _, _, err := clientgoscheme.Codecs.UniversalDeserializer().Decode(b, nil, &review); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			review.Status.Allowed = review.Spec.ResourceAttributes.Verb == "get"
+			if !review.Status.Allowed {
+				review.Status.Reason = "test denied"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(review)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	kubeConfig := generateKubeConfig(t, "default", &rest.Config{Host: ts.URL, BearerToken: "test-token"})
+	cl := fake.NewClientBuilder().WithObjects(newTestSecret(nil, map[string][]byte{"kubeconfig": kubeConfig})).Build()
+
+	report, err := Verify(context.TODO(), cl, testSecretName, ClientOptions{},
+		AccessCheck{Verb: "get", Resource: "secrets", Namespace: "default"},
+		AccessCheck{Verb: "delete", Group: "apps", Resource: "deployments"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.ServerVersion.GitVersion != "v1.36.2" {
+		t.Fatalf("got server version %s, want v1.36.2", report.ServerVersion.GitVersion)
+	}
+	want := []AccessResult{
+		{Check: AccessCheck{Verb: "get", Resource: "secrets", Namespace: "default"}, Allowed: true},
+		{Check: AccessCheck{Verb: "delete", Group: "apps", Resource: "deployments"}, Reason: "test denied"},
+	}
+	if diff := cmp.Diff(want, report.Access); diff != "" {
+		t.Fatalf("failed to verify access:\n%s", diff)
+	}
+	if report.Allowed() {
+		t.Fatal("report is allowed with denied checks")
+	}
+	if s := report.Denied()[0].String(); s != "delete deployments.apps" {
+		t.Fatalf("got denied check %q", s)
+	}
+}
+
+func TestVerifyUnreachable(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+	kubeConfig := generateKubeConfig(t, "default", &rest.Config{Host: ts.URL, BearerToken: "test-token"})
+	cl := fake.NewClientBuilder().WithObjects(newTestSecret(nil, map[string][]byte{"kubeconfig": kubeConfig})).Build()
+
+	_, err := Verify(context.TODO(), cl, testSecretName, ClientOptions{})
+	assertErrorMatch(t, `cluster in KubeConfig secret "default/test-kubeconfig" is not reachable: .*connection refused`, err)
+}
+
+func TestVerifyWithEnvTest(t *testing.T) {
+	testEnv := &envtest.Environment{}
+	testEnv.ControlPlane.GetAPIServer().Configure().Append("--authorization-mode=RBAC")
+	testCfg, err := testEnv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := testEnv.Stop(); err != nil {
+			t.Fatalf("failed to shutdown testEnv: %s", err)
+		}
+	}()
+
+	cl, err := client.New(testCfg, client.Options{Scheme: runtimeScheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretName := types.NamespacedName{
+		Namespace: "default",
+		Name:      "test-kubeconfig",
+	}
+	testWriteSecret(t, generateKubeConfig(t, "default", testCfg), cl, secretName)
+	writeRBACForSecret(t, cl, secretName)
+
+	report, err := Verify(context.TODO(), cl, secretName,
+		ClientOptions{
+			Key:  "kube.config",
+			REST: RESTOptions{Impersonate: &rest.ImpersonationConfig{UserName: "test-user"}}},
+		AccessCheck{Verb: "get", Resource: "secrets", Namespace: secretName.Namespace, Name: secretName.Name},
+		AccessCheck{Verb: "list", Resource: "secrets", Namespace: secretName.Namespace})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.ServerVersion.GitVersion == "" {
+		t.Fatal("failed to get the server version")
+	}
+	want := []AccessCheck{{Verb: "list", Resource: "secrets", Namespace: secretName.Namespace}}
+	if diff := cmp.Diff(want, report.Denied()); diff != "" {
+		t.Fatalf("failed to verify access:\n%s", diff)
+	}
+}
@@ -2,8 +2,10 @@ package names
 
 import (
 	"crypto/rand"
-	"fmt"
 	"math/big"
+	"strings"
+
+	"github.com/gitops-tools/pkg/sanitize"
 )
 
 const (
@@ -31,6 +33,9 @@ func New() *RandomGenerator {
 //
 // If the prefix ends with "-" this will be preserved in the trimmed string,
 // before adding the suffix.
+//
+// The prefix is sanitized with sanitize.SanitizeGitRef so that the generated
+// name is a valid branch name.
 func (g RandomGenerator) PrefixedName(prefix string) string {
 	charset := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	suffixLen := g.SuffixLen
@@ -50,9 +55,24 @@ func (g RandomGenerator) PrefixedName(prefix string) string {
 		b[i] = charset[n.Int64()]
 	}
 
+	return joinSuffix(prefix, string(b), g.MaxLength)
+}
+
+// joinSuffix appends the suffix to the prefix, trimming the prefix if the
+// result would exceed maxLength.
+//
+// If the prefix ends with "-" this will be preserved in the trimmed string,
+// before adding the suffix.
+//
+// The prefix is sanitized as part of the full name, as some rules e.g. no
+// trailing "/" only apply to the end of the name.
+func joinSuffix(prefix, suffix string, maxLength int) string {
+	if sanitized, err := sanitize.SanitizeGitRef(prefix + suffix); err == nil && strings.HasSuffix(sanitized, suffix) {
+		prefix = strings.TrimSuffix(sanitized, suffix)
+	}
 	lastChar := ""
-	if len(prefix)+suffixLen > g.MaxLength {
-		trimPoint := g.MaxLength - suffixLen
+	if len(prefix)+len(suffix) > maxLength {
+		trimPoint := maxLength - len(suffix)
 		if trimPoint < 0 {
 			trimPoint = 0
 		}
@@ -64,5 +84,5 @@ func (g RandomGenerator) PrefixedName(prefix string) string {
 			prefix = prefix[:trimPoint]
 		}
 	}
-	return fmt.Sprintf("%s%s%s", prefix, lastChar, string(b))
+	return prefix + lastChar + suffix
 }
@@ -1,7 +1,10 @@
 package names
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/gitops-tools/pkg/sanitize"
 )
 
 func TestGenerator(t *testing.T) {
@@ -32,3 +35,45 @@ func TestGenerator_trims_prefix(t *testing.T) {
 		// ok: suffix trimmed but preserved dash
 	}
 }
+
+func TestGenerator_sanitizes_prefix(t *testing.T) {
+	prefixTests := []struct {
+		prefix string
+		want   string
+	}{
+		{"my app update-", "my-app-update-"},
+		{"team/", "team/"},
+		{"team//.update..image.lock/", "team/update.image/"},
+		{"release~1^2:", "release-1-2-"},
+		{"bots@{", "bots-"},
+	}
+
+	for _, tt := range prefixTests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			g := New()
+
+			name := g.PrefixedName(tt.prefix)
+
+			if !strings.HasPrefix(name, tt.want) {
+				t.Errorf("generated name %q does not have sanitized prefix %q", name, tt.want)
+			}
+			if err := sanitize.ValidateGitRef(name); err != nil {
+				t.Errorf("generated name is not a valid ref: %s", err)
+			}
+		})
+	}
+}
+
+func TestGenerator_sanitizes_and_trims_prefix(t *testing.T) {
+	g := New()
+	g.MaxLength = 15
+
+	name := g.PrefixedName("my app..update-image-")
+
+	if len(name) != 15 {
+		t.Errorf("expected length 15 got %d", len(name))
+	}
+	if name[:10] != "my-app.up-" {
+		t.Errorf("generated name does not have sanitized prefix: %s", name)
+	}
+}
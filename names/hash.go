@@ -0,0 +1,55 @@
+package names
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const defaultHashSuffixLen = 10
+
+// HashGenerator generates a name suffix from a hash of the inputs.
+type HashGenerator struct {
+	MaxLength int
+	SuffixLen int
+}
+
+// NewHashGenerator creates and returns a HashGenerator.
+//
+// The generator is deterministic, the same inputs always generate the same
+// name.
+func NewHashGenerator() *HashGenerator {
+	return &HashGenerator{MaxLength: branchMaxLength, SuffixLen: defaultHashSuffixLen}
+}
+
+// PrefixedName generates a name from the prefix with a suffix derived from a
+// hash of the prefix.
+func (g HashGenerator) PrefixedName(prefix string) string {
+	return g.KeyedName(prefix)
+}
+
+// KeyedName generates a name from the prefix with a suffix derived from a hash
+// of the prefix and keys.
+//
+// If the prefix + the length of the suffix would exceed the MaxLength, then the
+// prefix will be sanitized and trimmed in the same way as the RandomGenerator.
+func (g HashGenerator) KeyedName(prefix string, keys ...string) string {
+	suffixLen := g.SuffixLen
+	if suffixLen <= 0 {
+		suffixLen = defaultHashSuffixLen
+	}
+
+	h := sha256.New()
+	_, _ = h.Write([]byte(prefix))
+	for _, k := range keys {
+		// The separator prevents different splits of the same string
+		// producing the same hash.
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(k))
+	}
+	suffix := hex.EncodeToString(h.Sum(nil))
+	if suffixLen < len(suffix) {
+		suffix = suffix[:suffixLen]
+	}
+
+	return joinSuffix(prefix, suffix, g.MaxLength)
+}
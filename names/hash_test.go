@@ -0,0 +1,51 @@
+package names
+
+import (
+	"testing"
+)
+
+var _ KeyedGenerator = (*HashGenerator)(nil)
+
+func TestHashGenerator(t *testing.T) {
+	g := NewHashGenerator()
+
+	name := g.KeyedName("testing-", "org/repo", "file.yaml", "content")
+
+	if name != "testing-0c4590f077" {
+		t.Errorf("generated name got %s, want %s", name, "testing-0c4590f077")
+	}
+	if again := g.KeyedName("testing-", "org/repo", "file.yaml", "content"); again != name {
+		t.Errorf("generated name is not stable, got %s, want %s", again, name)
+	}
+}
+
+func TestHashGenerator_different_keys(t *testing.T) {
+	g := NewHashGenerator()
+
+	names := map[string]bool{
+		g.PrefixedName("testing-"):                        true,
+		g.KeyedName("testing-", "org/repo", "file.yaml"):  true,
+		g.KeyedName("testing-", "org/repo", "other.yaml"): true,
+		g.KeyedName("testing-", "org/repofile.yaml"):      true,
+		g.KeyedName("testing-", "org/repo/", "file.yaml"): true,
+	}
+
+	if len(names) != 5 {
+		t.Errorf("expected 5 different names, got %v", names)
+	}
+}
+
+func TestHashGenerator_trims_prefix(t *testing.T) {
+	testString := "this-is-a-long-string-"
+	g := NewHashGenerator()
+	g.MaxLength = len(testString)
+
+	name := g.KeyedName(testString, "key")
+
+	if len(name) != len(testString) {
+		t.Errorf("expected length %d got %d", len(testString), len(name))
+	}
+	if name[:12] != "this-is-a-l-" {
+		t.Errorf("expected trimmed prefix with trailing '-', got %s", name)
+	}
+}
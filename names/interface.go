@@ -4,3 +4,10 @@ package names
 type Generator interface {
 	PrefixedName(s string) string
 }
+
+// KeyedGenerator is implemented by Generators that can derive the name from a
+// set of keys, the same prefix and keys always generate the same name.
+type KeyedGenerator interface {
+	Generator
+	KeyedName(prefix string, keys ...string) string
+}
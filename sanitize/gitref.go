@@ -0,0 +1,120 @@
+package sanitize
+
+import (
+	"strings"
+)
+
+// The rules for Git ref names from git check-ref-format.
+const (
+	gitRefEmptyRule         = "must not be empty"
+	gitRefAtRule            = "must not be the single character '@'"
+	gitRefSlashesRule       = "must not begin or end with '/' or contain consecutive slashes"
+	gitRefComponentDotRule  = "components must not begin with '.'"
+	gitRefComponentLockRule = "components must not end with '.lock'"
+	gitRefDoubleDotRule     = "must not contain '..'"
+	gitRefAtBraceRule       = "must not contain '@{'"
+	gitRefTrailingDotRule   = "must not end with '.'"
+	gitRefInvalidCharRule   = "must not contain control characters, space, '~', '^', ':', '?', '*', '[' or '\\'"
+)
+
+// ValidateGitRef checks a name against the rules for Git ref names used by
+// git check-ref-format with --allow-onelevel, and returns an InvalidNameError
+// listing every rule that the name violates.
+func ValidateGitRef(name string) error {
+	var violations []Violation
+	if name == "" {
+		return invalidRules("Git ref", name, []Violation{violation(ViolationEmpty, 0, gitRefEmptyRule)})
+	}
+	if name == "@" {
+		violations = append(violations, violation(ViolationReserved, 0, gitRefAtRule))
+	}
+	if strings.HasPrefix(name, "/") {
+		violations = append(violations, violation(ViolationInvalidStart, 0, gitRefSlashesRule))
+	}
+	violations = append(violations, sequenceViolations(name, "//", gitRefSlashesRule)...)
+	if strings.HasSuffix(name, "/") {
+		violations = append(violations, violation(ViolationInvalidEnd, len(name)-1, gitRefSlashesRule))
+	}
+	offset := 0
+	for _, component := range strings.Split(name, "/") {
+		if strings.HasPrefix(component, ".") {
+			violations = append(violations, violation(ViolationInvalidStart, offset, gitRefComponentDotRule))
+		}
+		if strings.HasSuffix(component, ".lock") {
+			violations = append(violations, violation(ViolationInvalidEnd, offset+len(component)-len(".lock"), gitRefComponentLockRule))
+		}
+		offset += len(component) + 1
+	}
+	violations = append(violations, sequenceViolations(name, "..", gitRefDoubleDotRule)...)
+	violations = append(violations, sequenceViolations(name, "@{", gitRefAtBraceRule)...)
+	if strings.HasSuffix(name, ".") {
+		violations = append(violations, violation(ViolationInvalidEnd, len(name)-1, gitRefTrailingDotRule))
+	}
+	violations = append(violations, invalidCharViolations(name, not(isInvalidGitRefChar), gitRefInvalidCharRule)...)
+
+	return invalidRulesOrNil("Git ref", name, violations)
+}
+
+// SanitizeGitRef sanitizes a string suitable for use as a Git ref name e.g. a
+// branch name.
+//
+// The name returned from here conforms to the rules for git check-ref-format:
+//   - control characters, space, '~', '^', ':', '?', '*', '[' and '\' are
+//     replaced with '-'
+//   - "@{" is replaced with '-'
+//   - consecutive dots are collapsed into a single '.'
+//   - leading, trailing and consecutive slashes are removed
+//   - leading '.' and '-', and trailing '.' and ".lock" are removed from each
+//     component, names that start with '-' are rejected by git on the command
+//     line
+//   - the single character '@' is replaced with '_'
+func SanitizeGitRef(name string) (string, error) {
+	if name == "" {
+		return "", invalidRules("Git ref", name, []Violation{violation(ViolationEmpty, 0, gitRefEmptyRule)})
+	}
+	sanitized := strings.Map(func(r rune) rune {
+		if isInvalidGitRefChar(r) {
+			return '-'
+		}
+		return r
+	}, name)
+	sanitized = strings.ReplaceAll(sanitized, "@{", "-")
+	for strings.Contains(sanitized, "..") {
+		sanitized = strings.ReplaceAll(sanitized, "..", ".")
+	}
+
+	var components []string
+	for _, component := range strings.Split(sanitized, "/") {
+		component = sanitizeGitRefComponent(component)
+		if component != "" {
+			components = append(components, component)
+		}
+	}
+	sanitized = strings.Join(components, "/")
+	if sanitized == "@" {
+		sanitized = "_"
+	}
+
+	if sanitized == "" {
+		return "", invalidNamef("Git ref %q sanitized is empty", name)
+	}
+	if err := ValidateGitRef(sanitized); err != nil {
+		return "", err
+	}
+	return sanitized, nil
+}
+
+func sanitizeGitRefComponent(s string) string {
+	s = strings.TrimLeft(s, ".-")
+	for {
+		trimmed := strings.TrimSuffix(strings.TrimRight(s, "."), ".lock")
+		if trimmed == s {
+			return s
+		}
+		s = trimmed
+	}
+}
+
+func isInvalidGitRefChar(r rune) bool {
+	return r < 0x20 || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r)
+}
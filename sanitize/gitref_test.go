@@ -0,0 +1,110 @@
+package sanitize
+
+import (
+	"testing"
+)
+
+func TestSanitizeGitRef(t *testing.T) {
+	sanitizeTests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "main", want: "main"},
+		{raw: "feature/update-image", want: "feature/update-image"},
+		{raw: "my app update", want: "my-app-update"},
+		{raw: "a~b^c:d?e*f[g\\h", want: "a-b-c-d-e-f-g-h"},
+		{raw: "tab\there", want: "tab-here"},
+		{raw: "release..1...2", want: "release.1.2"},
+		{raw: "branch@{1}", want: "branch-1}"},
+		{raw: "/leading/trailing/", want: "leading/trailing"},
+		{raw: "double//slash", want: "double/slash"},
+		{raw: ".hidden/.component", want: "hidden/component"},
+		{raw: "update.lock", want: "update"},
+		{raw: "update.lock/test.lock.lock", want: "update/test"},
+		{raw: "ends-with-dot.", want: "ends-with-dot"},
+		{raw: "component./next", want: "component/next"},
+		{raw: "équipe/données", want: "équipe/données"},
+		{raw: "@", want: "_"},
+		{raw: "/@/", want: "_"},
+		{raw: "@/main", want: "@/main"},
+		{raw: "-feature/--fix", want: "feature/fix"},
+		{raw: "~update", want: "update"},
+		{raw: ".-.hidden", want: "hidden"},
+		{raw: "feature-/fix-", want: "feature-/fix-"},
+	}
+
+	for _, tt := range sanitizeTests {
+		t.Run(tt.raw, func(t *testing.T) {
+			v, err := SanitizeGitRef(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != tt.want {
+				t.Fatalf("SanitizeGitRef() got %s, want %s", v, tt.want)
+			}
+			if err := ValidateGitRef(v); err != nil {
+				t.Fatalf("sanitized name is invalid: %s", err)
+			}
+		})
+	}
+}
+
+func TestSanitizeGitRef_errors(t *testing.T) {
+	sanitizeTests := []struct {
+		raw     string
+		wantErr string
+	}{
+		{raw: "", wantErr: `Git ref "" is invalid: must not be empty`},
+		{raw: "/./../", wantErr: `Git ref "/./../" sanitized is empty`},
+		{raw: "-/--", wantErr: `Git ref "-/--" sanitized is empty`},
+	}
+
+	for _, tt := range sanitizeTests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if _, err := SanitizeGitRef(tt.raw); err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("SanitizeGitRef() got %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateGitRef(t *testing.T) {
+	validateTests := []struct {
+		name    string
+		wantErr string
+	}{
+		{name: "main"},
+		{name: "feature/update-image"},
+		{name: "v1.0.0"},
+		{name: "component./next"},
+		{name: "", wantErr: `Git ref "" is invalid: must not be empty`},
+		{name: "@", wantErr: `Git ref "@" is invalid: must not be the single character '@'`},
+		{name: "/main", wantErr: `Git ref "/main" is invalid: must not begin or end with '/' or contain consecutive slashes`},
+		{name: "a//b", wantErr: `Git ref "a//b" is invalid: must not begin or end with '/' or contain consecutive slashes`},
+		{name: "a/.b/.c", wantErr: `Git ref "a/.b/.c" is invalid: components must not begin with '.'`},
+		{name: "a.lock/b", wantErr: `Git ref "a.lock/b" is invalid: components must not end with '.lock'`},
+		{name: "a..b", wantErr: `Git ref "a..b" is invalid: must not contain '..'`},
+		{name: "a@{b", wantErr: `Git ref "a@{b" is invalid: must not contain '@{'`},
+		{name: "a.", wantErr: `Git ref "a." is invalid: must not end with '.'`},
+		{name: "a b", wantErr: `Git ref "a b" is invalid: must not contain control characters, space, '~', '^', ':', '?', '*', '[' or '\'`},
+		{
+			name:    "/.test..lock @{1}.",
+			wantErr: `Git ref "/.test..lock @{1}." is invalid: must not begin or end with '/' or contain consecutive slashes; components must not begin with '.'; must not contain '..'; must not contain '@{'; must not end with '.'; must not contain control characters, space, '~', '^', ':', '?', '*', '[' or '\'`,
+		},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGitRef(tt.name)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateGitRef() got %s, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("ValidateGitRef() got %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
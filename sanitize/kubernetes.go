@@ -0,0 +1,422 @@
+package sanitize
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MaxLabelValueLength is the limit for label values, and the name part of
+// qualified names e.g. label and annotation keys.
+const MaxLabelValueLength = 63
+
+// MaxHelmReleaseNameLength is the limit for Helm release names.
+const MaxHelmReleaseNameLength = 53
+
+// The rules for the Kubernetes names and keys.
+const (
+	emptyRule           = "must not be empty"
+	startAlnumRule      = "must start with an alphanumeric character"
+	endAlnumRule        = "must end with an alphanumeric character"
+	dns1123LabelRule    = "must consist of lower case alphanumeric characters or '-'"
+	dns1123DomainRule   = "must consist of lower case alphanumeric characters, '-' or '.'"
+	dns1123SegmentsRule = "each '.' separated segment must start and end with an alphanumeric character"
+	labelValueRule      = "must consist of alphanumeric characters, '-', '_' or '.'"
+	qualifiedSlashRule  = "must contain at most one '/'"
+	startAlphaRule      = "must start with an alphabetic character"
+	dnsNameRule         = dns1123LabelRule
+	dnsDomainRule       = dns1123DomainRule
+	configMapDotsRule   = "must not be '.' or '..' or start with '..'"
+)
+
+// ValidateDNS1123Label checks a name against the rules for RFC 1123 DNS
+// labels, which unlike RFC 1035 labels can start with a digit, and returns an
+// InvalidNameError listing every rule that the name violates.
+func ValidateDNS1123Label(name string) error {
+	return invalidRulesOrNil("DNS-1123 label", name, dns1123LabelRules(name))
+}
+
+// SanitizeDNS1123Label sanitizes a string suitable for use in K8s resources that
+// require an RFC 1123 compatible label e.g. Namespace and Service names.
+//
+// The name is transliterated to ASCII with Transliterate and converted to lower
+// case, characters other than alphanumeric characters and '-' are replaced
+// with '-', and leading and trailing non-alphanumeric characters are removed.
+func SanitizeDNS1123Label(name string) (string, error) {
+	return Sanitizer{}.DNS1123Label(name)
+}
+
+// DNS1123Label sanitizes a string in the same way as SanitizeDNS1123Label.
+func (s Sanitizer) DNS1123Label(name string) (string, error) {
+	if name == "" {
+		return "", ErrEmptyName
+	}
+	output := sanitizeSegment(strings.ToLower(Transliterate(name, s.RuneMaps...)), isAllowedDNS)
+	if output == "" {
+		return "", invalidNamef("DNS-1123 label %q sanitized is empty", name)
+	}
+	if len(output) > MaxDNSNameLength {
+		return "", invalidNamef("DNS-1123 label %q exceeded maximum length of %v", name, MaxDNSNameLength)
+	}
+
+	if err := ValidateDNS1123Label(output); err != nil {
+		return "", err
+	}
+
+	return output, nil
+}
+
+// ValidateDNS1123Subdomain checks a name against the rules for RFC 1123 DNS
+// subdomains e.g. the names of most Kubernetes resources, and returns an
+// InvalidNameError listing every rule that the name violates.
+func ValidateDNS1123Subdomain(name string) error {
+	return invalidRulesOrNil("DNS-1123 subdomain", name, dns1123SubdomainRules(name, MaxK8SValueLength))
+}
+
+// SanitizeDNS1123Subdomain sanitizes a string suitable for use as an RFC 1123
+// DNS subdomain.
+//
+// Each '.' separated segment is sanitized in the same way as
+// SanitizeDNS1123Label, and empty segments are removed.
+func SanitizeDNS1123Subdomain(name string) (string, error) {
+	return Sanitizer{}.DNS1123Subdomain(name)
+}
+
+// DNS1123Subdomain sanitizes a string in the same way as
+// SanitizeDNS1123Subdomain.
+func (s Sanitizer) DNS1123Subdomain(name string) (string, error) {
+	return s.sanitizeDNS1123Subdomain("DNS-1123 subdomain", name, MaxK8SValueLength)
+}
+
+// ValidateHelmReleaseName checks a name against the rules for Helm release
+// names, which are DNS-1123 subdomains of at most 53 characters, and returns
+// an InvalidNameError listing every rule that the name violates.
+func ValidateHelmReleaseName(name string) error {
+	return invalidRulesOrNil("Helm release name", name, dns1123SubdomainRules(name, MaxHelmReleaseNameLength))
+}
+
+// SanitizeHelmReleaseName sanitizes a string suitable for use as a Helm
+// release name, in the same way as SanitizeDNS1123Subdomain.
+func SanitizeHelmReleaseName(name string) (string, error) {
+	return Sanitizer{}.HelmReleaseName(name)
+}
+
+// HelmReleaseName sanitizes a string in the same way as
+// SanitizeHelmReleaseName.
+func (s Sanitizer) HelmReleaseName(name string) (string, error) {
+	return s.sanitizeDNS1123Subdomain("Helm release name", name, MaxHelmReleaseNameLength)
+}
+
+// ValidateLabelValue checks a value against the rules for label values and
+// returns an InvalidNameError listing every rule that the value violates.
+//
+// Empty label values are valid.
+func ValidateLabelValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	return invalidRulesOrNil("label value", value, labelValueRules(value))
+}
+
+// SanitizeLabelValue sanitizes a string suitable for use as a label value.
+//
+// The value is transliterated to ASCII with Transliterate, characters other
+// than alphanumeric characters, '-', '_' and '.' are replaced with '-', and
+// leading and trailing non-alphanumeric characters are removed.
+//
+// Unlike the other sanitizers, an empty value is valid, and is returned
+// without error.
+func SanitizeLabelValue(value string) (string, error) {
+	return Sanitizer{}.LabelValue(value)
+}
+
+// LabelValue sanitizes a string in the same way as SanitizeLabelValue.
+func (s Sanitizer) LabelValue(value string) (string, error) {
+	output := sanitizeSegment(Transliterate(value, s.RuneMaps...), isAllowedLabelValue)
+	if len(output) > MaxLabelValueLength {
+		return "", invalidNamef("label value %q exceeded maximum length of %v", value, MaxLabelValueLength)
+	}
+
+	if err := ValidateLabelValue(output); err != nil {
+		return "", err
+	}
+
+	return output, nil
+}
+
+// ValidateLabelKey checks a key against the rules for label keys, which are
+// qualified names with an optional DNS-1123 subdomain prefix e.g.
+// app.kubernetes.io/name, and returns an InvalidNameError listing every rule
+// that the key violates.
+func ValidateLabelKey(key string) error {
+	return invalidRulesOrNil("label key", key, qualifiedNameRules(key))
+}
+
+// SanitizeLabelKey sanitizes a string suitable for use as a label key.
+//
+// The prefix is sanitized in the same way as SanitizeDNS1123Subdomain, and the
+// name in the same way as SanitizeLabelValue.
+func SanitizeLabelKey(key string) (string, error) {
+	return Sanitizer{}.LabelKey(key)
+}
+
+// LabelKey sanitizes a string in the same way as SanitizeLabelKey.
+func (s Sanitizer) LabelKey(key string) (string, error) {
+	return s.sanitizeQualifiedName("label key", key, ValidateLabelKey)
+}
+
+// ValidateAnnotationKey checks a key against the rules for annotation keys and
+// returns an InvalidNameError listing every rule that the key violates.
+//
+// Annotation keys are qualified names in the same way as label keys, but the
+// prefix is not case-sensitive.
+func ValidateAnnotationKey(key string) error {
+	return invalidRulesOrNil("annotation key", key, qualifiedNameRules(strings.ToLower(key)))
+}
+
+// SanitizeAnnotationKey sanitizes a string suitable for use as an annotation
+// key, in the same way as SanitizeLabelKey.
+func SanitizeAnnotationKey(key string) (string, error) {
+	return Sanitizer{}.AnnotationKey(key)
+}
+
+// AnnotationKey sanitizes a string in the same way as SanitizeAnnotationKey.
+func (s Sanitizer) AnnotationKey(key string) (string, error) {
+	return s.sanitizeQualifiedName("annotation key", key, ValidateAnnotationKey)
+}
+
+// ValidateConfigMapKey checks a key against the rules for the keys of
+// ConfigMap and Secret data, and returns an InvalidNameError listing every rule
+// that the key violates.
+func ValidateConfigMapKey(key string) error {
+	if key == "" {
+		return invalidRules("data key", key, emptyViolation())
+	}
+	var violations []Violation
+	if len(key) > MaxK8SValueLength {
+		violations = append(violations, tooLongViolation(MaxK8SValueLength))
+	}
+	violations = append(violations, invalidCharViolations(key, isAllowedLabelValue, labelValueRule)...)
+	switch {
+	case key == "." || key == "..":
+		violations = append(violations, violation(ViolationReserved, 0, configMapDotsRule))
+	case strings.HasPrefix(key, ".."):
+		violations = append(violations, violation(ViolationInvalidStart, 0, configMapDotsRule))
+	}
+	return invalidRulesOrNil("data key", key, violations)
+}
+
+// SanitizeConfigMapKey sanitizes a string suitable for use as the key of
+// ConfigMap and Secret data e.g. a filename.
+//
+// The key is transliterated to ASCII with Transliterate, characters other than
+// alphanumeric characters, '-', '_' and '.' are replaced with '-', and the
+// leading '.' characters are removed from keys that are '.' or start with '..'.
+func SanitizeConfigMapKey(key string) (string, error) {
+	return Sanitizer{}.ConfigMapKey(key)
+}
+
+// ConfigMapKey sanitizes a string in the same way as SanitizeConfigMapKey.
+func (s Sanitizer) ConfigMapKey(key string) (string, error) {
+	if key == "" {
+		return "", invalidRules("data key", key, emptyViolation())
+	}
+	output := strings.Map(replaceInvalid(isAllowedLabelValue), Transliterate(key, s.RuneMaps...))
+	if output == "." || strings.HasPrefix(output, "..") {
+		output = strings.TrimLeft(output, ".")
+	}
+	if output == "" {
+		return "", invalidNamef("data key %q sanitized is empty", key)
+	}
+	if len(output) > MaxK8SValueLength {
+		return "", invalidNamef("data key %q exceeded maximum length of %v", key, MaxK8SValueLength)
+	}
+
+	if err := ValidateConfigMapKey(output); err != nil {
+		return "", err
+	}
+
+	return output, nil
+}
+
+func dns1123LabelRules(name string) []Violation {
+	if name == "" {
+		return emptyViolation()
+	}
+	var violations []Violation
+	if len(name) > MaxDNSNameLength {
+		violations = append(violations, tooLongViolation(MaxDNSNameLength))
+	}
+	violations = append(violations, invalidCharViolations(name, isAllowedDNS1123, dns1123LabelRule)...)
+	return append(violations, alnumEndsRules(name)...)
+}
+
+func dns1123SubdomainRules(name string, maxLength int) []Violation {
+	if name == "" {
+		return emptyViolation()
+	}
+	var violations []Violation
+	if len(name) > maxLength {
+		violations = append(violations, tooLongViolation(maxLength))
+	}
+	violations = append(violations, invalidCharViolations(name, func(r rune) bool {
+		return r == '.' || isAllowedDNS1123(r)
+	}, dns1123DomainRule)...)
+
+	offset := 0
+	for _, segment := range strings.Split(name, ".") {
+		for _, v := range alnumEndsRules(segment) {
+			violations = append(violations, violation(v.Code, v.Position+offset, dns1123SegmentsRule))
+		}
+		offset += len(segment) + 1
+	}
+	return violations
+}
+
+func labelValueRules(value string) []Violation {
+	var violations []Violation
+	if len(value) > MaxLabelValueLength {
+		violations = append(violations, tooLongViolation(MaxLabelValueLength))
+	}
+	violations = append(violations, invalidCharViolations(value, isAllowedLabelValue, labelValueRule)...)
+	return append(violations, alnumEndsRules(value)...)
+}
+
+// qualifiedNameRules returns the rules violated by a name with an optional
+// DNS-1123 subdomain prefix, e.g. example.com/my-name.
+func qualifiedNameRules(key string) []Violation {
+	if key == "" {
+		return emptyViolation()
+	}
+	prefix, name, hasPrefix := strings.Cut(key, "/")
+	if !hasPrefix {
+		prefix, name = "", key
+	}
+
+	var violations []Violation
+	offset := 0
+	if hasPrefix {
+		violations = offsetViolations(dns1123SubdomainRules(prefix, MaxK8SValueLength), 0, "prefix ")
+		offset = len(prefix) + 1
+	}
+	for i, r := range name {
+		if r == '/' {
+			violations = append(violations, violation(ViolationInvalidCharacter, offset+i, qualifiedSlashRule))
+		}
+	}
+	nameViolations := emptyViolation()
+	if name != "" {
+		// The extra '/' characters have already been reported.
+		nameViolations = slices.DeleteFunc(labelValueRules(name), func(v Violation) bool {
+			return v.Code == ViolationInvalidCharacter && name[v.Position] == '/'
+		})
+	}
+	return append(violations, offsetViolations(nameViolations, offset, "name ")...)
+}
+
+func alnumEndsRules(s string) []Violation {
+	if s == "" {
+		return []Violation{violation(ViolationInvalidStart, 0, startAlnumRule), violation(ViolationInvalidEnd, 0, endAlnumRule)}
+	}
+	var violations []Violation
+	if !isAlphanumeric(rune(s[0])) {
+		violations = append(violations, violation(ViolationInvalidStart, 0, startAlnumRule))
+	}
+	if !isAlphanumeric(rune(s[len(s)-1])) {
+		violations = append(violations, violation(ViolationInvalidEnd, len(s)-1, endAlnumRule))
+	}
+	return violations
+}
+
+func (s Sanitizer) sanitizeDNS1123Subdomain(kind, name string, maxLength int) (string, error) {
+	if name == "" {
+		return "", invalidRules(kind, name, emptyViolation())
+	}
+	var segments []string
+	for _, segment := range strings.Split(strings.ToLower(Transliterate(name, s.RuneMaps...)), ".") {
+		if segment = sanitizeSegment(segment, isAllowedDNS); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	output := strings.Join(segments, ".")
+	if output == "" {
+		return "", invalidNamef("%s %q sanitized is empty", kind, name)
+	}
+	if len(output) > maxLength {
+		return "", invalidNamef("%s %q exceeded maximum length of %v", kind, name, maxLength)
+	}
+
+	if err := invalidRulesOrNil(kind, output, dns1123SubdomainRules(output, maxLength)); err != nil {
+		return "", err
+	}
+
+	return output, nil
+}
+
+func (s Sanitizer) sanitizeQualifiedName(kind, key string, validate func(string) error) (string, error) {
+	if key == "" {
+		return "", invalidRules(kind, key, emptyViolation())
+	}
+	prefix, name, hasPrefix := strings.Cut(key, "/")
+	if !hasPrefix {
+		name = prefix
+	}
+
+	output := sanitizeSegment(Transliterate(name, s.RuneMaps...), isAllowedLabelValue)
+	if output == "" {
+		return "", invalidNamef("%s %q sanitized is empty", kind, key)
+	}
+	if len(output) > MaxLabelValueLength {
+		return "", invalidNamef("%s %q exceeded maximum length of %v", kind, key, MaxLabelValueLength)
+	}
+	if hasPrefix && prefix != "" {
+		sanitizedPrefix, err := s.sanitizeDNS1123Subdomain(kind+" prefix", prefix, MaxK8SValueLength)
+		if err != nil {
+			return "", err
+		}
+		output = sanitizedPrefix + "/" + output
+	}
+
+	if err := validate(output); err != nil {
+		return "", err
+	}
+
+	return output, nil
+}
+
+// sanitizeSegment replaces characters that are not allowed with '-' and
+// removes leading and trailing non-alphanumeric characters.
+//
+// The string must already be transliterated, and lower cased after it is
+// transliterated where upper case is not allowed, as replacements can be upper
+// case e.g. "™" becomes "TM".
+func sanitizeSegment(s string, allowed func(rune) bool) string {
+	return strings.TrimFunc(strings.Map(replaceInvalid(allowed), s), not(isAlphanumeric))
+}
+
+func replaceInvalid(allowed func(rune) bool) func(rune) rune {
+	return func(r rune) rune {
+		if allowed(r) {
+			return r
+		}
+		return '-'
+	}
+}
+
+func maxLengthRule(n int) string {
+	return fmt.Sprintf("must be no more than %d characters", n)
+}
+
+func isAllowedDNS1123(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-'
+}
+
+func isAllowedLabelValue(c rune) bool {
+	return isAlphanumeric(c) || c == '-' || c == '_' || c == '.'
+}
+
+func not(pred func(rune) bool) func(rune) bool {
+	return func(r rune) bool {
+		return !pred(r)
+	}
+}
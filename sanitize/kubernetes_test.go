@@ -0,0 +1,174 @@
+package sanitize
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidateKubernetesNames(t *testing.T) {
+	validateTests := []struct {
+		desc     string
+		validate func(string) error
+		name     string
+		wantErr  string
+	}{
+		{desc: "label", validate: ValidateDNS1123Label, name: "my-service"},
+		{desc: "label starting with digit", validate: ValidateDNS1123Label, name: "0-service"},
+		{desc: "empty label", validate: ValidateDNS1123Label, name: "", wantErr: `DNS-1123 label "" is invalid: must not be empty`},
+		{
+			desc: "invalid label", validate: ValidateDNS1123Label, name: "-My.Service",
+			wantErr: `DNS-1123 label "-My.Service" is invalid: must consist of lower case alphanumeric characters or '-'; must start with an alphanumeric character`,
+		},
+		{
+			desc: "long label", validate: ValidateDNS1123Label, name: strings.Repeat("a", 64),
+			wantErr: `DNS-1123 label "a{64}" is invalid: must be no more than 63 characters`,
+		},
+
+		{desc: "subdomain", validate: ValidateDNS1123Subdomain, name: "example.com"},
+		{
+			desc: "invalid subdomain", validate: ValidateDNS1123Subdomain, name: "example-.Com",
+			wantErr: `DNS-1123 subdomain "example-.Com" is invalid: must consist of lower case alphanumeric characters, '-' or '.'; each '.' separated segment must start and end with an alphanumeric character`,
+		},
+		{
+			desc: "empty segment", validate: ValidateDNS1123Subdomain, name: "example..com",
+			wantErr: `DNS-1123 subdomain "example..com" is invalid: each '.' separated segment must start and end with an alphanumeric character`,
+		},
+
+		{desc: "helm release", validate: ValidateHelmReleaseName, name: "my-release.v1"},
+		{
+			desc: "long helm release", validate: ValidateHelmReleaseName, name: strings.Repeat("a", 54),
+			wantErr: `Helm release name "a{54}" is invalid: must be no more than 53 characters`,
+		},
+
+		{desc: "label value", validate: ValidateLabelValue, name: "My_Value.1"},
+		{desc: "empty label value", validate: ValidateLabelValue, name: ""},
+		{
+			desc: "invalid label value", validate: ValidateLabelValue, name: "_my value/",
+			wantErr: `label value "_my value/" is invalid: must consist of alphanumeric characters, '-', '_' or '.'; must start with an alphanumeric character; must end with an alphanumeric character`,
+		},
+
+		{desc: "label key", validate: ValidateLabelKey, name: "app"},
+		{desc: "prefixed label key", validate: ValidateLabelKey, name: "app.kubernetes.io/name"},
+		{desc: "empty label key", validate: ValidateLabelKey, name: "", wantErr: `label key "" is invalid: must not be empty`},
+		{
+			desc: "invalid label key prefix", validate: ValidateLabelKey, name: "Example.com/name",
+			wantErr: `label key "Example.com/name" is invalid: prefix must consist of lower case alphanumeric characters, '-' or '.'`,
+		},
+		{
+			desc: "empty label key prefix and name", validate: ValidateLabelKey, name: "/",
+			wantErr: `label key "/" is invalid: prefix must not be empty; name must not be empty`,
+		},
+		{
+			desc: "label key with too many slashes", validate: ValidateLabelKey, name: "a/b/c",
+			wantErr: `label key "a/b/c" is invalid: must contain at most one '/'`,
+		},
+
+		{desc: "annotation key", validate: ValidateAnnotationKey, name: "Example.com/Name"},
+		{
+			desc: "invalid annotation key", validate: ValidateAnnotationKey, name: "example.com/-name",
+			wantErr: `annotation key "example.com/-name" is invalid: name must start with an alphanumeric character`,
+		},
+
+		{desc: "data key", validate: ValidateConfigMapKey, name: "config.yaml"},
+		{desc: "dotfile data key", validate: ValidateConfigMapKey, name: ".env"},
+		{desc: "empty data key", validate: ValidateConfigMapKey, name: "", wantErr: `data key "" is invalid: must not be empty`},
+		{
+			desc: "invalid data key", validate: ValidateConfigMapKey, name: "../config file",
+			wantErr: `data key "../config file" is invalid: must consist of alphanumeric characters, '-', '_' or '.'; must not be '.' or '..' or start with '..'`,
+		},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.desc, func(t *testing.T) {
+			err := tt.validate(tt.name)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("got %s, want nil", err)
+				}
+				return
+			}
+			if _, ok := err.(InvalidNameError); !ok {
+				t.Fatalf("got %#v, want an InvalidNameError", err)
+			}
+			if want := expandRepeats(tt.wantErr); err.Error() != want {
+				t.Fatalf("got %v, want %s", err, want)
+			}
+		})
+	}
+}
+
+func TestSanitizeKubernetesNames(t *testing.T) {
+	sanitizeTests := []struct {
+		desc     string
+		sanitize func(string) (string, error)
+		validate func(string) error
+		raw      string
+		want     string
+	}{
+		{desc: "label", sanitize: SanitizeDNS1123Label, validate: ValidateDNS1123Label, raw: "0 My_Service!", want: "0-my-service"},
+		{desc: "subdomain", sanitize: SanitizeDNS1123Subdomain, validate: ValidateDNS1123Subdomain, raw: "-My..Example_.Com", want: "my.example.com"},
+		{desc: "helm release", sanitize: SanitizeHelmReleaseName, validate: ValidateHelmReleaseName, raw: "My Release", want: "my-release"},
+		{desc: "label value", sanitize: SanitizeLabelValue, validate: ValidateLabelValue, raw: "_My Value/1.0_", want: "My-Value-1.0"},
+		{desc: "empty label value", sanitize: SanitizeLabelValue, validate: ValidateLabelValue, raw: "---", want: ""},
+		{desc: "label key", sanitize: SanitizeLabelKey, validate: ValidateLabelKey, raw: "My App", want: "My-App"},
+		{desc: "prefixed label key", sanitize: SanitizeLabelKey, validate: ValidateLabelKey, raw: "Example.COM/My Name", want: "example.com/My-Name"},
+		{desc: "empty label key prefix", sanitize: SanitizeLabelKey, validate: ValidateLabelKey, raw: "/name", want: "name"},
+		{desc: "annotation key", sanitize: SanitizeAnnotationKey, validate: ValidateAnnotationKey, raw: "example.com/last applied", want: "example.com/last-applied"},
+		{desc: "data key", sanitize: SanitizeConfigMapKey, validate: ValidateConfigMapKey, raw: "config file.yaml", want: "config-file.yaml"},
+		{desc: "dotfile data key", sanitize: SanitizeConfigMapKey, validate: ValidateConfigMapKey, raw: ".env", want: ".env"},
+		{desc: "parent data key", sanitize: SanitizeConfigMapKey, validate: ValidateConfigMapKey, raw: "../config", want: "-config"},
+	}
+
+	for _, tt := range sanitizeTests {
+		t.Run(tt.desc, func(t *testing.T) {
+			v, err := tt.sanitize(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != tt.want {
+				t.Fatalf("got %s, want %s", v, tt.want)
+			}
+			if err := tt.validate(v); err != nil {
+				t.Fatalf("sanitized name is invalid: %s", err)
+			}
+		})
+	}
+}
+
+func TestSanitizeKubernetesNames_errors(t *testing.T) {
+	sanitizeTests := []struct {
+		desc     string
+		sanitize func(string) (string, error)
+		raw      string
+		wantErr  string
+	}{
+		{desc: "empty label", sanitize: SanitizeDNS1123Label, raw: "", wantErr: `DNS name can not be empty`},
+		{desc: "sanitized label is empty", sanitize: SanitizeDNS1123Label, raw: "$$$", wantErr: `DNS-1123 label "$$$" sanitized is empty`},
+		{desc: "long label", sanitize: SanitizeDNS1123Label, raw: strings.Repeat("a", 64), wantErr: `DNS-1123 label "a{64}" exceeded maximum length of 63`},
+		{desc: "sanitized subdomain is empty", sanitize: SanitizeDNS1123Subdomain, raw: "-.-", wantErr: `DNS-1123 subdomain "-.-" sanitized is empty`},
+		{desc: "long helm release", sanitize: SanitizeHelmReleaseName, raw: strings.Repeat("a", 54), wantErr: `Helm release name "a{54}" exceeded maximum length of 53`},
+		{desc: "long label value", sanitize: SanitizeLabelValue, raw: strings.Repeat("a", 64), wantErr: `label value "a{64}" exceeded maximum length of 63`},
+		{desc: "empty label key", sanitize: SanitizeLabelKey, raw: "", wantErr: `label key "" is invalid: must not be empty`},
+		{desc: "sanitized label key is empty", sanitize: SanitizeLabelKey, raw: "example.com/$$$", wantErr: `label key "example.com/$$$" sanitized is empty`},
+		{desc: "sanitized prefix is empty", sanitize: SanitizeAnnotationKey, raw: "$$$/name", wantErr: `annotation key prefix "$$$" sanitized is empty`},
+		{desc: "sanitized data key is empty", sanitize: SanitizeConfigMapKey, raw: "..", wantErr: `data key ".." sanitized is empty`},
+	}
+
+	for _, tt := range sanitizeTests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if _, err := tt.sanitize(tt.raw); err == nil || err.Error() != expandRepeats(tt.wantErr) {
+				t.Fatalf("got %v, want %s", err, expandRepeats(tt.wantErr))
+			}
+		})
+	}
+}
+
+// expandRepeats expands "a{64}" to 64 'a' characters to keep the long names in
+// the tests readable.
+func expandRepeats(s string) string {
+	for _, n := range []int{54, 64} {
+		s = strings.ReplaceAll(s, fmt.Sprintf("a{%d}", n), strings.Repeat("a", n))
+	}
+	return s
+}
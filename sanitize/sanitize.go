@@ -1,6 +1,8 @@
 package sanitize
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"strings"
 )
@@ -12,13 +14,23 @@ var ErrEmptyName = invalidName("DNS name can not be empty")
 // be used as DNS names per RFC 1035.
 const MaxDNSNameLength = 63
 
+// hashLength is the number of characters of the hash that are appended to
+// truncated names.
+const hashLength = 8
+
 // MaxK8sValueLength is the limit for names that can be used as DNS subdomain
 // values per RFC 1123.
 // https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#dns-subdomain-names
 const MaxK8SValueLength = 253
 
-// InvalidNameError is returned when a name can't be sanitized.
+// InvalidNameError is returned when a name can't be sanitized, or is invalid.
+//
+// When the error is returned from one of the Validate functions, the
+// Violations describe every rule that the Name violates.
 type InvalidNameError struct {
+	Name       string
+	Violations []Violation
+
 	msg string
 }
 
@@ -26,19 +38,144 @@ func (m InvalidNameError) Error() string {
 	return m.msg
 }
 
+// Is returns true if the target is an InvalidNameError with the same Name and
+// message, so that errors.Is can be used to check for ErrEmptyName.
+func (m InvalidNameError) Is(target error) bool {
+	t, ok := target.(InvalidNameError)
+	return ok && t.Name == m.Name && t.msg == m.msg
+}
+
 // SanitizeDNSName sanitizes a string suitable for use in K8s resources that
 // require a DNS 1035 compatible name.
 //
+// The name is transliterated to ASCII with Transliterate, and whitespace and
+// '_' are replaced with '-'.
+//
 // The name returned from here must conform to following rules (as per RFC 1035):
 //   - length must be <= 63 characters
 //   - must be all lower case alphanumeric characters or '-'
 //   - must start with an alphabet
 //   - must end with an alphanumeric character
 func SanitizeDNSName(name string) (string, error) {
+	return Sanitizer{}.DNSName(name)
+}
+
+// DNSName sanitizes a string in the same way as SanitizeDNSName.
+func (s Sanitizer) DNSName(name string) (string, error) {
+	output, err := sanitizeDNSLabel(name, s.RuneMaps)
+	if err != nil {
+		return "", err
+	}
+
+	if len(output) > MaxDNSNameLength {
+		return "", invalidNamef("DNS name %q exceeded maximum length of %v", name, MaxDNSNameLength)
+	}
+
+	return output, nil
+}
+
+// ValidateDNSName checks a name against the rules for RFC 1035 DNS labels used
+// by SanitizeDNSName, and returns an InvalidNameError with every violation.
+func ValidateDNSName(name string) error {
+	return invalidRulesOrNil("DNS name", name, dnsNameRules(name))
+}
+
+// SanitizeDNSNameWithHash sanitizes a string in the same way as
+// SanitizeDNSName, but rather than returning an error if the sanitized name is
+// too long, it is truncated and a short hash of the original name is appended.
+//
+// Different names that share the same truncated prefix will generate different
+// names.
+func SanitizeDNSNameWithHash(name string) (string, error) {
+	return Sanitizer{}.DNSNameWithHash(name)
+}
+
+// DNSNameWithHash sanitizes a string in the same way as
+// SanitizeDNSNameWithHash.
+func (s Sanitizer) DNSNameWithHash(name string) (string, error) {
+	output, err := sanitizeDNSLabel(name, s.RuneMaps)
+	if err != nil {
+		return "", err
+	}
+
+	if len(output) > MaxDNSNameLength {
+		output = truncateWithHash(output, name, MaxDNSNameLength)
+	}
+
+	return output, nil
+}
+
+// DNS subdomains are DNS labels separated by '.', max 253 characters.
+func SanitizeDNSDomain(name string) (string, error) {
+	return Sanitizer{}.DNSDomain(name)
+}
+
+// DNSDomain sanitizes a string in the same way as SanitizeDNSDomain.
+func (s Sanitizer) DNSDomain(name string) (string, error) {
+	output, err := sanitizeDNSDomain(name, s.DNSName)
+	if err != nil {
+		return "", err
+	}
+
+	if len(output) > MaxK8SValueLength {
+		return "", invalidNamef("DNS name %q exceeded maximum length of %v", name, MaxK8SValueLength)
+	}
+
+	return output, nil
+}
+
+// ValidateDNSDomain checks a name against the rules for DNS subdomains made up
+// of RFC 1035 DNS labels used by SanitizeDNSDomain, and returns an
+// InvalidNameError with every violation.
+func ValidateDNSDomain(name string) error {
+	if name == "" {
+		return invalidRules("DNS domain", name, emptyViolation())
+	}
+	var violations []Violation
+	if len(name) > MaxK8SValueLength {
+		violations = append(violations, tooLongViolation(MaxK8SValueLength))
+	}
+	violations = append(violations, invalidCharViolations(name, func(r rune) bool {
+		return r == '.' || isAllowedDNS1123(r)
+	}, dnsDomainRule)...)
+
+	offset := 0
+	for _, label := range strings.Split(name, ".") {
+		violations = append(violations, offsetViolations(dnsLabelRules(label), offset, "each '.' separated label ")...)
+		offset += len(label) + 1
+	}
+
+	return invalidRulesOrNil("DNS domain", name, violations)
+}
+
+// SanitizeDNSDomainWithHash sanitizes a string in the same way as
+// SanitizeDNSDomain, but rather than returning an error if the sanitized
+// domain, or any of the labels are too long, they are truncated and a short
+// hash of the original is appended.
+func SanitizeDNSDomainWithHash(name string) (string, error) {
+	return Sanitizer{}.DNSDomainWithHash(name)
+}
+
+// DNSDomainWithHash sanitizes a string in the same way as
+// SanitizeDNSDomainWithHash.
+func (s Sanitizer) DNSDomainWithHash(name string) (string, error) {
+	output, err := sanitizeDNSDomain(name, s.DNSNameWithHash)
+	if err != nil {
+		return "", err
+	}
+
+	if len(output) > MaxK8SValueLength {
+		output = truncateWithHash(output, name, MaxK8SValueLength)
+	}
+
+	return output, nil
+}
+
+func sanitizeDNSLabel(name string, maps []RuneMap) (string, error) {
 	if name == "" {
 		return "", ErrEmptyName
 	}
-	runes := []rune(strings.ToLower(name))
+	runes := []rune(strings.ToLower(strings.Map(separatorsToDash, Transliterate(name, maps...))))
 	start := findIndex(runes, isAlpha)
 	if start == len(runes) {
 		return "", invalidNamef("DNS name %q does not start with a valid character", name)
@@ -56,19 +193,14 @@ func SanitizeDNSName(name string) (string, error) {
 		}
 	}
 
-	if len(output) > MaxDNSNameLength {
-		return "", invalidNamef("DNS name %q exceeded maximum length of %v", name, MaxDNSNameLength)
-	}
-
-	if len(output) == 0 {
+	if strings.Trim(output, "-") == "" {
 		return "", invalidNamef("DNS name %q sanitized is empty", name)
 	}
 
 	return output, nil
 }
 
-// DNS subdomains are DNS labels separated by '.', max 253 characters.
-func SanitizeDNSDomain(name string) (string, error) {
+func sanitizeDNSDomain(name string, sanitizeLabel func(string) (string, error)) (string, error) {
 	if name == "" {
 		return "", ErrEmptyName
 	}
@@ -77,7 +209,7 @@ func SanitizeDNSDomain(name string) (string, error) {
 	firstSegment := true
 	output := ""
 	for _, segment := range dnsSegments {
-		sanitized, err := SanitizeDNSName(segment)
+		sanitized, err := sanitizeLabel(segment)
 		if err != nil {
 			return "", err
 		}
@@ -89,11 +221,58 @@ func SanitizeDNSDomain(name string) (string, error) {
 		output += "." + sanitized
 	}
 
-	if len(output) > MaxK8SValueLength {
-		return "", invalidNamef("DNS name %q exceeded maximum length of %v", name, MaxK8SValueLength)
+	return output, nil
+}
+
+func dnsNameRules(name string) []Violation {
+	if name == "" {
+		return emptyViolation()
+	}
+	var violations []Violation
+	if len(name) > MaxDNSNameLength {
+		violations = append(violations, tooLongViolation(MaxDNSNameLength))
 	}
+	violations = append(violations, invalidCharViolations(name, isAllowedDNS1123, dnsNameRule)...)
+	return append(violations, dnsLabelEndsRules(name)...)
+}
 
-	return output, nil
+func dnsLabelRules(label string) []Violation {
+	if label == "" {
+		return emptyViolation()
+	}
+	var violations []Violation
+	if len(label) > MaxDNSNameLength {
+		violations = append(violations, tooLongViolation(MaxDNSNameLength))
+	}
+	return append(violations, dnsLabelEndsRules(label)...)
+}
+
+func dnsLabelEndsRules(label string) []Violation {
+	var violations []Violation
+	if !isAlpha(rune(label[0])) {
+		violations = append(violations, violation(ViolationInvalidStart, 0, startAlphaRule))
+	}
+	if !isAlphanumeric(rune(label[len(label)-1])) {
+		violations = append(violations, violation(ViolationInvalidEnd, len(label)-1, endAlnumRule))
+	}
+	return violations
+}
+
+// truncateWithHash truncates the sanitized name to fit the hash of the
+// original name within maxLength.
+//
+// The truncated name never ends with a '-' or '.' before the hash is appended,
+// and the final DNS label is kept within MaxDNSNameLength.
+func truncateWithHash(sanitized, original string, maxLength int) string {
+	h := sha256.Sum256([]byte(original))
+	suffix := "-" + hex.EncodeToString(h[:])[:hashLength]
+
+	truncated := strings.TrimRight(sanitized[:maxLength-len(suffix)], "-.")
+	if lastDot := strings.LastIndex(truncated, "."); len(truncated)-lastDot-1 > MaxDNSNameLength-len(suffix) {
+		truncated = strings.TrimRight(truncated[:lastDot+1+MaxDNSNameLength-len(suffix)], "-.")
+	}
+
+	return truncated + suffix
 }
 
 func isAlpha(c rune) bool {
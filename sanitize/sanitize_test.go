@@ -101,6 +101,14 @@ func TestSanitizeDNSName_errors(t *testing.T) {
 			raw:     "$$$$$$",
 			wantErr: `DNS name "$$$$$$" sanitized is empty`,
 		},
+		{
+			raw:     "команда",
+			wantErr: `DNS name "команда" sanitized is empty`,
+		},
+		{
+			raw:     " _ ",
+			wantErr: `DNS name " _ " sanitized is empty`,
+		},
 	}
 
 	for _, tt := range sanitizeTests {
@@ -206,3 +214,155 @@ func TestSanitizeDNSDomain_errors(t *testing.T) {
 		})
 	}
 }
+
+func TestSanitizeDNSNameWithHash(t *testing.T) {
+	sanitizeTests := []struct {
+		raw  string
+		want string
+	}{
+		{
+			raw:  "$edgeAgent",
+			want: "edgeagent",
+		},
+		{
+			raw:  "ABCDEFGHIJABCDEFGHIJABCDEFGHIJABCDEFGHIJABCDEFGHIJABCDEFGHIJABC",
+			want: "abcdefghijabcdefghijabcdefghijabcdefghijabcdefghijabcdefghijabc",
+		},
+		{
+			raw:  "ABCDEFGHIJABCDEFGHIJABCDEFGHIJABCDEFGHIJABCDEFGHIJABCDEFGHIJABCDEFGHIJ",
+			want: "abcdefghijabcdefghijabcdefghijabcdefghijabcdefghijabcd-f9460b0a",
+		},
+		{
+			raw:  "ABCDEFGHIJABCDEFGHIJABCDEFGHIJABCDEFGHIJABCDEFGHIJABCDEFGHIJABCDEFGHIK",
+			want: "abcdefghijabcdefghijabcdefghijabcdefghijabcdefghijabcd-11acd7cd",
+		},
+		// the cut lands on a '-'
+		{
+			raw:  "ABCDEFGHIJABCDEFGHIJABCDEFGHIJABCDEFGHIJABCDEFGHIJA---DEFGHIJABCDEFGHIJ",
+			want: "abcdefghijabcdefghijabcdefghijabcdefghijabcdefghija-180c6f0b",
+		},
+	}
+
+	for _, tt := range sanitizeTests {
+		t.Run(tt.raw, func(t *testing.T) {
+			v, err := SanitizeDNSNameWithHash(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != tt.want {
+				t.Fatalf("SanitizeDNSNameWithHash() got %s, want %s", v, tt.want)
+			}
+			if _, err := SanitizeDNSName(v); err != nil {
+				t.Fatalf("SanitizeDNSNameWithHash() returned an invalid name: %s", err)
+			}
+		})
+	}
+}
+
+func TestSanitizeDNSNameWithHash_errors(t *testing.T) {
+	if _, err := SanitizeDNSNameWithHash("$$$$$$"); err.Error() != `DNS name "$$$$$$" sanitized is empty` {
+		t.Fatalf("SanitizeDNSNameWithHash() got %s", err)
+	}
+}
+
+func TestSanitizeDNSDomainWithHash(t *testing.T) {
+	longLabel := strings.Repeat("abcdefghij", 7)
+	longDomain := strings.TrimSuffix(strings.Repeat(strings.Repeat("abcdefghij", 6)+".", 5), ".")
+
+	sanitizeTests := []struct {
+		raw  string
+		want string
+	}{
+		{
+			raw:  "---a-0---.org",
+			want: "a-0.org",
+		},
+		{
+			raw:  longLabel + ".com",
+			want: "abcdefghijabcdefghijabcdefghijabcdefghijabcdefghijabcd-09c57627.com",
+		},
+		// the cut lands on the '.' at the end of a label
+		{
+			raw:  longDomain,
+			want: strings.Repeat(strings.Repeat("abcdefghij", 6)+".", 3) + "abcdefghijabcdefghijabcdefghijabcdefghijabcdefghijabcd-1a167719",
+		},
+	}
+
+	for _, tt := range sanitizeTests {
+		t.Run(tt.raw, func(t *testing.T) {
+			v, err := SanitizeDNSDomainWithHash(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != tt.want {
+				t.Fatalf("SanitizeDNSDomainWithHash() got %s, want %s", v, tt.want)
+			}
+			if _, err := SanitizeDNSDomain(v); err != nil {
+				t.Fatalf("SanitizeDNSDomainWithHash() returned an invalid name: %s", err)
+			}
+		})
+	}
+}
+
+func TestValidateDNSName(t *testing.T) {
+	validateTests := []struct {
+		name    string
+		wantErr string
+	}{
+		{name: "edgeagent"},
+		{name: "a-0"},
+		{name: "", wantErr: `DNS name "" is invalid: must not be empty`},
+		{name: "0-a", wantErr: `DNS name "0-a" is invalid: must start with an alphabetic character`},
+		{name: "Edge_Agent-", wantErr: `DNS name "Edge_Agent-" is invalid: must consist of lower case alphanumeric characters or '-'; must end with an alphanumeric character`},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDNSName(tt.name)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateDNSName() got %s, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("ValidateDNSName() got %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDNSDomain(t *testing.T) {
+	validateTests := []struct {
+		name    string
+		wantErr string
+	}{
+		{name: "edgeagent"},
+		{name: "a-0.example.org"},
+		{name: "", wantErr: `DNS domain "" is invalid: must not be empty`},
+		{name: "a..org", wantErr: `DNS domain "a..org" is invalid: each '.' separated label must not be empty`},
+		{
+			name:    "0a.b-.1c",
+			wantErr: `DNS domain "0a.b-.1c" is invalid: each '.' separated label must start with an alphabetic character; each '.' separated label must end with an alphanumeric character`,
+		},
+		{
+			name:    "A.org." + strings.Repeat("a", 64),
+			wantErr: `DNS domain "A.org.` + strings.Repeat("a", 64) + `" is invalid: must consist of lower case alphanumeric characters, '-' or '.'; each '.' separated label must be no more than 63 characters`,
+		},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDNSDomain(tt.name)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateDNSDomain() got %s, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("ValidateDNSDomain() got %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package sanitize
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// RuneMap maps runes to ASCII replacements when transliterating.
+//
+// Replacements are looked up for the rune, and then for the lower case rune, in
+// which case the replacement is upper cased.
+type RuneMap map[rune]string
+
+// LatinRuneMap folds the common Latin letters and ligatures that are not
+// decomposed into an ASCII letter and combining marks.
+var LatinRuneMap = RuneMap{
+	'æ': "ae",
+	'œ': "oe",
+	'ø': "o",
+	'ß': "ss",
+	'ẞ': "SS",
+	'đ': "d",
+	'ð': "d",
+	'ħ': "h",
+	'ı': "i",
+	'ł': "l",
+	'ŀ': "l",
+	'ŋ': "ng",
+	'þ': "th",
+	'ŧ': "t",
+}
+
+// CyrillicRuneMap transliterates the Russian, Ukrainian and Belarusian
+// Cyrillic alphabets to ASCII.
+//
+// This is not used by the sanitizers by default, but can be provided to a
+// Sanitizer.
+//
+//	sanitize.Sanitizer{RuneMaps: []sanitize.RuneMap{sanitize.CyrillicRuneMap}}.DNSName(name)
+var CyrillicRuneMap = RuneMap{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e",
+	'ё': "yo", 'є': "ye", 'ж': "zh", 'з': "z", 'и': "i", 'і': "i", 'ї': "yi",
+	'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+	'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ў': "u", 'ф': "f", 'х': "kh",
+	'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Sanitizer sanitizes names in the same way as the Sanitize functions, but
+// transliterates with the RuneMaps before LatinRuneMap, so that names in other
+// scripts can be sanitized.
+//
+// The zero value sanitizes in the same way as the Sanitize functions.
+type Sanitizer struct {
+	RuneMaps []RuneMap
+}
+
+// Transliterate converts a string to ASCII where possible.
+//
+// Runes are first looked up in the provided maps in order, and then in
+// LatinRuneMap, runes that aren't found are decomposed, and any combining
+// marks e.g. accents are removed, "Équipe ﬁnances" becomes "Equipe finances".
+//
+// Runes that can't be transliterated are returned unchanged for the
+// sanitizers to remove.
+func Transliterate(s string, maps ...RuneMap) string {
+	// The maps are copied so that the caller's backing array is not modified.
+	maps = append(maps[:len(maps):len(maps)], LatinRuneMap)
+
+	var b strings.Builder
+	for _, r := range s {
+		if replacement, ok := lookupRune(r, maps); ok {
+			b.WriteString(replacement)
+			continue
+		}
+		for _, d := range norm.NFKD.String(string(r)) {
+			if !unicode.Is(unicode.Mn, d) {
+				b.WriteRune(d)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func lookupRune(r rune, maps []RuneMap) (string, bool) {
+	for _, m := range maps {
+		if replacement, ok := m[r]; ok {
+			return replacement, true
+		}
+		if lower := unicode.ToLower(r); lower != r {
+			if replacement, ok := m[lower]; ok {
+				return strings.ToUpper(replacement), true
+			}
+		}
+	}
+	return "", false
+}
+
+// separatorsToDash maps whitespace and '_' to '-'.
+func separatorsToDash(r rune) rune {
+	if unicode.IsSpace(r) || r == '_' {
+		return '-'
+	}
+	return r
+}
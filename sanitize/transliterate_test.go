@@ -0,0 +1,154 @@
+package sanitize
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestTransliterate(t *testing.T) {
+	transliterateTests := []struct {
+		raw  string
+		maps []RuneMap
+		want string
+	}{
+		{raw: "Équipe Données", want: "Equipe Donnees"},
+		{raw: "ﬁnances", want: "finances"},
+		{raw: "Æsir Œuvre Straße Øresund Łódź", want: "AEsir OEuvre Strasse Oresund Lodz"},
+		{raw: "naïve café", want: "naive cafe"},
+		{raw: "команда", want: "команда"},
+		{raw: "Команда Щука", maps: []RuneMap{CyrillicRuneMap}, want: "Komanda SHCHuka"},
+		{raw: "æ", maps: []RuneMap{{'æ': "a"}}, want: "a"},
+		{raw: "チーム", want: "チーム"},
+	}
+
+	for _, tt := range transliterateTests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if v := Transliterate(tt.raw, tt.maps...); v != tt.want {
+				t.Fatalf("Transliterate() got %s, want %s", v, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeTransliterated(t *testing.T) {
+	sanitizeTests := []struct {
+		raw      string
+		sanitize func(string) (string, error)
+		want     string
+	}{
+		{raw: "Équipe Données", sanitize: SanitizeDNSName, want: "equipe-donnees"},
+		{raw: "my_team\tname", sanitize: SanitizeDNSName, want: "my-team-name"},
+		{raw: "équipe.données.fr", sanitize: SanitizeDNSDomain, want: "equipe.donnees.fr"},
+		{raw: Transliterate("Команда", CyrillicRuneMap), sanitize: SanitizeDNSName, want: "komanda"},
+		{raw: "Données", sanitize: SanitizeLabelValue, want: "Donnees"},
+		{raw: "Øresund", sanitize: SanitizeDNS1123Label, want: "oresund"},
+		{raw: "données.yaml", sanitize: SanitizeConfigMapKey, want: "donnees.yaml"},
+		{raw: "Acme™", sanitize: SanitizeDNSName, want: "acmetm"},
+		{raw: "Acme™", sanitize: SanitizeDNS1123Label, want: "acmetm"},
+		{raw: "Acme™.io", sanitize: SanitizeDNS1123Subdomain, want: "acmetm.io"},
+		{raw: "Acme™", sanitize: SanitizeHelmReleaseName, want: "acmetm"},
+	}
+
+	for _, tt := range sanitizeTests {
+		t.Run(tt.raw, func(t *testing.T) {
+			v, err := tt.sanitize(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != tt.want {
+				t.Fatalf("got %s, want %s", v, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizerRuneMaps(t *testing.T) {
+	cyrillic := Sanitizer{RuneMaps: []RuneMap{CyrillicRuneMap}}
+	japanese := Sanitizer{RuneMaps: []RuneMap{{'チ': "chi", 'ー': "", 'ム': "mu"}}}
+	sanitizeTests := []struct {
+		desc     string
+		raw      string
+		sanitize func(string) (string, error)
+		want     string
+	}{
+		{desc: "DNS name", raw: "Команда Щука", sanitize: cyrillic.DNSName, want: "komanda-shchuka"},
+		{desc: "DNS name with hash", raw: "Команда", sanitize: cyrillic.DNSNameWithHash, want: "komanda"},
+		{desc: "DNS domain", raw: "Команда.рф", sanitize: cyrillic.DNSDomain, want: "komanda.rf"},
+		{desc: "DNS domain with hash", raw: "Команда.рф", sanitize: cyrillic.DNSDomainWithHash, want: "komanda.rf"},
+		{desc: "DNS-1123 label", raw: "Щука", sanitize: cyrillic.DNS1123Label, want: "shchuka"},
+		{desc: "DNS-1123 subdomain", raw: "Команда.Щука", sanitize: cyrillic.DNS1123Subdomain, want: "komanda.shchuka"},
+		{desc: "Helm release name", raw: "Щука", sanitize: cyrillic.HelmReleaseName, want: "shchuka"},
+		{desc: "label value", raw: "Щука", sanitize: cyrillic.LabelValue, want: "SHCHuka"},
+		{desc: "label key", raw: "команда.рф/Щука", sanitize: cyrillic.LabelKey, want: "komanda.rf/SHCHuka"},
+		{desc: "annotation key", raw: "команда.рф/Щука", sanitize: cyrillic.AnnotationKey, want: "komanda.rf/SHCHuka"},
+		{desc: "data key", raw: "Щука.yaml", sanitize: cyrillic.ConfigMapKey, want: "SHCHuka.yaml"},
+		{desc: "DNS name with CJK", raw: "チーム", sanitize: japanese.DNSName, want: "chimu"},
+		{desc: "DNS-1123 label with CJK", raw: "チーム", sanitize: japanese.DNS1123Label, want: "chimu"},
+		{desc: "label value with CJK", raw: "チーム", sanitize: japanese.LabelValue, want: "chimu"},
+		{desc: "zero value", raw: "Øresund", sanitize: Sanitizer{}.DNS1123Label, want: "oresund"},
+	}
+
+	for _, tt := range sanitizeTests {
+		t.Run(tt.desc, func(t *testing.T) {
+			v, err := tt.sanitize(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != tt.want {
+				t.Fatalf("got %s, want %s", v, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransliterateDoesNotModifyMaps(t *testing.T) {
+	maps := make([]RuneMap, 1, 2)
+	maps[0] = CyrillicRuneMap
+
+	if v := Transliterate("Щука", maps...); v != "SHCHuka" {
+		t.Fatalf("got %s, want SHCHuka", v)
+	}
+
+	if spare := maps[:2][1]; spare != nil {
+		t.Fatalf("transliterating modified the maps, got %v", spare)
+	}
+}
+
+func TestSanitizerConcurrently(t *testing.T) {
+	maps := make([]RuneMap, 1, 2)
+	maps[0] = CyrillicRuneMap
+	s := Sanitizer{RuneMaps: maps}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if _, err := s.DNS1123Label("Щука"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestSanitizeNonLatin_errors(t *testing.T) {
+	sanitizers := map[string]func(string) (string, error){
+		"DNS name":           SanitizeDNSName,
+		"DNS name with hash": SanitizeDNSNameWithHash,
+		"DNS domain":         SanitizeDNSDomain,
+		"DNS-1123 label":     SanitizeDNS1123Label,
+		"DNS-1123 subdomain": SanitizeDNS1123Subdomain,
+		"Helm release name":  SanitizeHelmReleaseName,
+		"label key":          SanitizeLabelKey,
+		"annotation key":     SanitizeAnnotationKey,
+	}
+
+	for desc, sanitize := range sanitizers {
+		t.Run(desc, func(t *testing.T) {
+			for _, raw := range []string{"команда", "チーム"} {
+				if v, err := sanitize(raw); err == nil {
+					t.Errorf("sanitize(%q) got %q, want an error without a RuneMap", raw, v)
+				}
+			}
+		})
+	}
+}
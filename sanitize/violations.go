@@ -0,0 +1,115 @@
+package sanitize
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ViolationCode is a machine-readable identifier for the kind of rule that a
+// name violates.
+type ViolationCode string
+
+const (
+	// ViolationEmpty is reported when a name is empty.
+	ViolationEmpty ViolationCode = "Empty"
+	// ViolationTooLong is reported when a name exceeds the maximum length, the
+	// position is the first character beyond the limit.
+	ViolationTooLong ViolationCode = "TooLong"
+	// ViolationInvalidCharacter is reported for each character in a name that
+	// is not allowed.
+	ViolationInvalidCharacter ViolationCode = "InvalidCharacter"
+	// ViolationInvalidStart is reported when a name, or a part of the name
+	// starts with a character that is not allowed.
+	ViolationInvalidStart ViolationCode = "InvalidStart"
+	// ViolationInvalidEnd is reported when a name, or a part of the name ends
+	// with a character or suffix that is not allowed.
+	ViolationInvalidEnd ViolationCode = "InvalidEnd"
+	// ViolationInvalidSequence is reported when a name contains a sequence of
+	// characters that is not allowed e.g. ".." in a Git ref.
+	ViolationInvalidSequence ViolationCode = "InvalidSequence"
+	// ViolationReserved is reported when a name is a reserved value e.g. "@" for
+	// Git refs.
+	ViolationReserved ViolationCode = "Reserved"
+)
+
+// Violation describes a rule that a name violates.
+type Violation struct {
+	Code     ViolationCode
+	Position int    // byte offset of the violation in the name
+	Rule     string // e.g. must start with an alphanumeric character
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s at position %d: %s", v.Code, v.Position, v.Rule)
+}
+
+func violation(code ViolationCode, position int, rule string) Violation {
+	return Violation{Code: code, Position: position, Rule: rule}
+}
+
+func emptyViolation() []Violation {
+	return []Violation{violation(ViolationEmpty, 0, emptyRule)}
+}
+
+func tooLongViolation(maxLength int) Violation {
+	return violation(ViolationTooLong, maxLength, maxLengthRule(maxLength))
+}
+
+// invalidCharViolations returns a violation at each character in s that isn't
+// allowed.
+func invalidCharViolations(s string, allowed func(rune) bool, rule string) []Violation {
+	var violations []Violation
+	for i, r := range s {
+		if !allowed(r) {
+			violations = append(violations, violation(ViolationInvalidCharacter, i, rule))
+		}
+	}
+	return violations
+}
+
+// sequenceViolations returns a violation at each occurrence of seq in s.
+func sequenceViolations(s, seq string, rule string) []Violation {
+	var violations []Violation
+	for i := 0; i < len(s); i++ {
+		j := strings.Index(s[i:], seq)
+		if j < 0 {
+			break
+		}
+		i += j
+		violations = append(violations, violation(ViolationInvalidSequence, i, rule))
+	}
+	return violations
+}
+
+// offsetViolations moves the violations for a part of a name to the position
+// of the part in the name, and adds a prefix to the rules e.g. "prefix ".
+func offsetViolations(violations []Violation, offset int, rulePrefix string) []Violation {
+	moved := make([]Violation, len(violations))
+	for i, v := range violations {
+		moved[i] = violation(v.Code, v.Position+offset, rulePrefix+v.Rule)
+	}
+	return moved
+}
+
+func invalidRules(kind, name string, violations []Violation) InvalidNameError {
+	// The same rule can be violated at several positions, but is only
+	// described once in the message.
+	var rules []string
+	for _, v := range violations {
+		if !slices.Contains(rules, v.Rule) {
+			rules = append(rules, v.Rule)
+		}
+	}
+	err := invalidNamef("%s %q is invalid: %s", kind, name, strings.Join(rules, "; "))
+	err.Name = name
+	err.Violations = violations
+	return err
+}
+
+func invalidRulesOrNil(kind, name string, violations []Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	return invalidRules(kind, name, violations)
+}
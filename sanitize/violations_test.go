@@ -0,0 +1,180 @@
+package sanitize
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidateViolations(t *testing.T) {
+	violationTests := []struct {
+		desc     string
+		validate func(string) error
+		name     string
+		want     []Violation
+	}{
+		{
+			desc: "DNS name", validate: ValidateDNSName, name: "0_a-",
+			want: []Violation{
+				{Code: ViolationInvalidCharacter, Position: 1, Rule: "must consist of lower case alphanumeric characters or '-'"},
+				{Code: ViolationInvalidStart, Position: 0, Rule: "must start with an alphabetic character"},
+				{Code: ViolationInvalidEnd, Position: 3, Rule: "must end with an alphanumeric character"},
+			},
+		},
+		{
+			desc: "DNS domain", validate: ValidateDNSDomain, name: "a.b-.c",
+			want: []Violation{
+				{Code: ViolationInvalidEnd, Position: 3, Rule: "each '.' separated label must end with an alphanumeric character"},
+			},
+		},
+		{
+			desc: "DNS-1123 subdomain", validate: ValidateDNS1123Subdomain, name: "a.-b",
+			want: []Violation{
+				{Code: ViolationInvalidStart, Position: 2, Rule: "each '.' separated segment must start and end with an alphanumeric character"},
+			},
+		},
+		{
+			desc: "label key", validate: ValidateLabelKey, name: "Example.com/-name",
+			want: []Violation{
+				{Code: ViolationInvalidCharacter, Position: 0, Rule: "prefix must consist of lower case alphanumeric characters, '-' or '.'"},
+				{Code: ViolationInvalidStart, Position: 12, Rule: "name must start with an alphanumeric character"},
+			},
+		},
+		{
+			desc: "data key", validate: ValidateConfigMapKey, name: "..",
+			want: []Violation{
+				{Code: ViolationReserved, Position: 0, Rule: "must not be '.' or '..' or start with '..'"},
+			},
+		},
+		{
+			desc: "Git ref", validate: ValidateGitRef, name: "main/.test..lock @{1}",
+			want: []Violation{
+				{Code: ViolationInvalidStart, Position: 5, Rule: "components must not begin with '.'"},
+				{Code: ViolationInvalidSequence, Position: 10, Rule: "must not contain '..'"},
+				{Code: ViolationInvalidSequence, Position: 17, Rule: "must not contain '@{'"},
+				{Code: ViolationInvalidCharacter, Position: 16, Rule: "must not contain control characters, space, '~', '^', ':', '?', '*', '[' or '\\'"},
+			},
+		},
+		{
+			desc: "DNS name with several violations", validate: ValidateDNSName, name: "A_b_",
+			want: []Violation{
+				{Code: ViolationInvalidCharacter, Position: 0, Rule: "must consist of lower case alphanumeric characters or '-'"},
+				{Code: ViolationInvalidCharacter, Position: 1, Rule: "must consist of lower case alphanumeric characters or '-'"},
+				{Code: ViolationInvalidCharacter, Position: 3, Rule: "must consist of lower case alphanumeric characters or '-'"},
+				{Code: ViolationInvalidEnd, Position: 3, Rule: "must end with an alphanumeric character"},
+			},
+		},
+		{
+			desc: "DNS domain with several violations", validate: ValidateDNSDomain, name: "a-.B.-c-",
+			want: []Violation{
+				{Code: ViolationInvalidCharacter, Position: 3, Rule: "must consist of lower case alphanumeric characters, '-' or '.'"},
+				{Code: ViolationInvalidEnd, Position: 1, Rule: "each '.' separated label must end with an alphanumeric character"},
+				{Code: ViolationInvalidStart, Position: 5, Rule: "each '.' separated label must start with an alphabetic character"},
+				{Code: ViolationInvalidEnd, Position: 7, Rule: "each '.' separated label must end with an alphanumeric character"},
+			},
+		},
+		{
+			desc: "DNS-1123 label with several violations", validate: ValidateDNS1123Label, name: "-A_b",
+			want: []Violation{
+				{Code: ViolationInvalidCharacter, Position: 1, Rule: "must consist of lower case alphanumeric characters or '-'"},
+				{Code: ViolationInvalidCharacter, Position: 2, Rule: "must consist of lower case alphanumeric characters or '-'"},
+				{Code: ViolationInvalidStart, Position: 0, Rule: "must start with an alphanumeric character"},
+			},
+		},
+		{
+			desc: "DNS-1123 subdomain with several violations", validate: ValidateDNS1123Subdomain, name: "-a.b-.c_",
+			want: []Violation{
+				{Code: ViolationInvalidCharacter, Position: 7, Rule: "must consist of lower case alphanumeric characters, '-' or '.'"},
+				{Code: ViolationInvalidStart, Position: 0, Rule: "each '.' separated segment must start and end with an alphanumeric character"},
+				{Code: ViolationInvalidEnd, Position: 4, Rule: "each '.' separated segment must start and end with an alphanumeric character"},
+				{Code: ViolationInvalidEnd, Position: 7, Rule: "each '.' separated segment must start and end with an alphanumeric character"},
+			},
+		},
+		{
+			desc: "Helm release name with several violations", validate: ValidateHelmReleaseName, name: "a-.-b",
+			want: []Violation{
+				{Code: ViolationInvalidEnd, Position: 1, Rule: "each '.' separated segment must start and end with an alphanumeric character"},
+				{Code: ViolationInvalidStart, Position: 3, Rule: "each '.' separated segment must start and end with an alphanumeric character"},
+			},
+		},
+		{
+			desc: "label value with several violations", validate: ValidateLabelValue, name: "-a b!",
+			want: []Violation{
+				{Code: ViolationInvalidCharacter, Position: 2, Rule: "must consist of alphanumeric characters, '-', '_' or '.'"},
+				{Code: ViolationInvalidCharacter, Position: 4, Rule: "must consist of alphanumeric characters, '-', '_' or '.'"},
+				{Code: ViolationInvalidStart, Position: 0, Rule: "must start with an alphanumeric character"},
+				{Code: ViolationInvalidEnd, Position: 4, Rule: "must end with an alphanumeric character"},
+			},
+		},
+		{
+			desc: "label key with several violations", validate: ValidateLabelKey, name: "a-.b/c/d-",
+			want: []Violation{
+				{Code: ViolationInvalidEnd, Position: 1, Rule: "prefix each '.' separated segment must start and end with an alphanumeric character"},
+				{Code: ViolationInvalidCharacter, Position: 6, Rule: "must contain at most one '/'"},
+				{Code: ViolationInvalidEnd, Position: 8, Rule: "name must end with an alphanumeric character"},
+			},
+		},
+		{
+			desc: "annotation key with several violations", validate: ValidateAnnotationKey, name: "-A.b-/c d!",
+			want: []Violation{
+				{Code: ViolationInvalidStart, Position: 0, Rule: "prefix each '.' separated segment must start and end with an alphanumeric character"},
+				{Code: ViolationInvalidEnd, Position: 4, Rule: "prefix each '.' separated segment must start and end with an alphanumeric character"},
+				{Code: ViolationInvalidCharacter, Position: 7, Rule: "name must consist of alphanumeric characters, '-', '_' or '.'"},
+				{Code: ViolationInvalidCharacter, Position: 9, Rule: "name must consist of alphanumeric characters, '-', '_' or '.'"},
+				{Code: ViolationInvalidEnd, Position: 9, Rule: "name must end with an alphanumeric character"},
+			},
+		},
+		{
+			desc: "data key with several violations", validate: ValidateConfigMapKey, name: "..a b:c",
+			want: []Violation{
+				{Code: ViolationInvalidCharacter, Position: 3, Rule: "must consist of alphanumeric characters, '-', '_' or '.'"},
+				{Code: ViolationInvalidCharacter, Position: 5, Rule: "must consist of alphanumeric characters, '-', '_' or '.'"},
+				{Code: ViolationInvalidStart, Position: 0, Rule: "must not be '.' or '..' or start with '..'"},
+			},
+		},
+		{
+			desc: "Git ref with several violations", validate: ValidateGitRef, name: "/a//.b.lock/.c..d..e/",
+			want: []Violation{
+				{Code: ViolationInvalidStart, Position: 0, Rule: "must not begin or end with '/' or contain consecutive slashes"},
+				{Code: ViolationInvalidSequence, Position: 2, Rule: "must not begin or end with '/' or contain consecutive slashes"},
+				{Code: ViolationInvalidEnd, Position: 20, Rule: "must not begin or end with '/' or contain consecutive slashes"},
+				{Code: ViolationInvalidStart, Position: 4, Rule: "components must not begin with '.'"},
+				{Code: ViolationInvalidEnd, Position: 6, Rule: "components must not end with '.lock'"},
+				{Code: ViolationInvalidStart, Position: 12, Rule: "components must not begin with '.'"},
+				{Code: ViolationInvalidSequence, Position: 14, Rule: "must not contain '..'"},
+				{Code: ViolationInvalidSequence, Position: 17, Rule: "must not contain '..'"},
+			},
+		},
+	}
+
+	for _, tt := range violationTests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var invalid InvalidNameError
+			if err := tt.validate(tt.name); !errors.As(err, &invalid) {
+				t.Fatalf("got %#v, want an InvalidNameError", err)
+			}
+			if invalid.Name != tt.name {
+				t.Errorf("got Name %q, want %q", invalid.Name, tt.name)
+			}
+			if diff := cmp.Diff(tt.want, invalid.Violations); diff != "" {
+				t.Fatalf("failed to get violations:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInvalidNameErrorIs(t *testing.T) {
+	_, err := SanitizeDNSName("")
+	if !errors.Is(err, ErrEmptyName) {
+		t.Fatalf("got %#v, want ErrEmptyName", err)
+	}
+
+	_, err = SanitizeDNSName("0")
+	if errors.Is(err, ErrEmptyName) {
+		t.Fatalf("got %#v, want an error other than ErrEmptyName", err)
+	}
+	if !errors.Is(err, err) {
+		t.Fatalf("failed to match %#v against itself", err)
+	}
+}
@@ -0,0 +1,212 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+var _ SecretGetter = (*CachingSecretGetter)(nil)
+
+var (
+	cacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "gitops_secret_cache_hits_total",
+		Help: "Number of secret tokens returned from the CachingSecretGetter cache.",
+	})
+	cacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "gitops_secret_cache_misses_total",
+		Help: "Number of secret tokens that were not found in the CachingSecretGetter cache.",
+	})
+)
+
+// RegisterMetrics registers the CachingSecretGetter metrics with the
+// registerer, e.g. the controller-runtime metrics Registry.
+//
+//	err := secrets.RegisterMetrics(metrics.Registry)
+func RegisterMetrics(r prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{cacheHits, cacheMisses} {
+		if err := r.Register(c); err != nil {
+			return fmt.Errorf("failed to register secret cache metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// CachingSecretGetter is an implementation of SecretGetter that caches the
+// tokens from another SecretGetter.
+//
+// Tokens are cached for the TTL, errors are not cached.
+//
+// Hits and misses are recorded in the gitops_secret_cache_hits_total and
+// gitops_secret_cache_misses_total metrics, which are exported once they are
+// registered with RegisterMetrics.
+type CachingSecretGetter struct {
+	getter SecretGetter
+	ttl    time.Duration
+	clock  func() time.Time
+
+	mu      sync.Mutex
+	entries map[types.NamespacedName]map[string]cacheEntry
+	// pending tracks the secrets with tokens that are being fetched, so that
+	// tokens fetched before an invalidation are not cached.
+	pending map[types.NamespacedName]*pendingFetches
+}
+
+// pendingFetches counts the fetches for a secret that are in progress, and
+// the generation is incremented when the secret is invalidated.
+type pendingFetches struct {
+	count      int
+	generation uint64
+}
+
+type cacheEntry struct {
+	token   string
+	expires time.Time
+}
+
+// CacheOption is an option func for the CachingSecretGetter creation function.
+type CacheOption func(c *CachingSecretGetter)
+
+// CacheClock is an option func for the CachingSecretGetter creation function
+// that replaces the clock that is used to expire cached tokens.
+func CacheClock(clock func() time.Time) CacheOption {
+	return func(c *CachingSecretGetter) {
+		c.clock = clock
+	}
+}
+
+// NewCachingSecretGetter creates and returns a CachingSecretGetter that
+// caches the tokens from the getter for the TTL.
+func NewCachingSecretGetter(getter SecretGetter, ttl time.Duration, opts ...CacheOption) *CachingSecretGetter {
+	c := &CachingSecretGetter{
+		getter:  getter,
+		ttl:     ttl,
+		clock:   time.Now,
+		entries: map[types.NamespacedName]map[string]cacheEntry{},
+		pending: map[types.NamespacedName]*pendingFetches{},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// SecretToken returns the cached token for the namespaced secret and key, or
+// looks it up with the wrapped SecretGetter and caches it.
+func (c *CachingSecretGetter) SecretToken(ctx context.Context, id types.NamespacedName, key string) (string, error) {
+	token, generation, ok := c.cached(id, key)
+	if ok {
+		cacheHits.Inc()
+		return token, nil
+	}
+	cacheMisses.Inc()
+
+	token, err := c.getter.SecretToken(ctx, id, key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	invalidated := c.finishFetch(id, generation)
+	if err != nil {
+		return "", err
+	}
+	if invalidated {
+		// The secret was invalidated while the token was being fetched, so
+		// the token may already be stale.
+		return token, nil
+	}
+	if c.entries[id] == nil {
+		c.entries[id] = map[string]cacheEntry{}
+	}
+	c.entries[id][key] = cacheEntry{token: token, expires: c.clock().Add(c.ttl)}
+
+	return token, nil
+}
+
+// Invalidate removes all the cached tokens for the namespaced secret.
+func (c *CachingSecretGetter) Invalidate(id types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, id)
+	if p, ok := c.pending[id]; ok {
+		p.generation++
+	}
+}
+
+// Watch registers an event handler with a Secret informer that invalidates
+// the cached tokens for a Secret when its resourceVersion changes, or it is
+// deleted.
+//
+// The informer can be obtained from a controller-runtime cache.
+//
+//	informer, err := mgr.GetCache().GetInformer(ctx, &corev1.Secret{})
+func (c *CachingSecretGetter) Watch(informer cache.Informer) error {
+	_, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj any) {
+			oldSecret, ok := oldObj.(metav1.Object)
+			if !ok {
+				return
+			}
+			newSecret, ok := newObj.(metav1.Object)
+			if !ok || oldSecret.GetResourceVersion() == newSecret.GetResourceVersion() {
+				return
+			}
+			c.Invalidate(types.NamespacedName{Name: newSecret.GetName(), Namespace: newSecret.GetNamespace()})
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if secret, ok := obj.(metav1.Object); ok {
+				c.Invalidate(types.NamespacedName{Name: secret.GetName(), Namespace: secret.GetNamespace()})
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add secret cache event handler: %w", err)
+	}
+	return nil
+}
+
+// cached returns the cached token, or if the token is not cached, starts a
+// fetch and returns the current generation of the secret.
+//
+// Every fetch that is started must be finished with finishFetch.
+func (c *CachingSecretGetter) cached(id types.NamespacedName, key string) (string, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[id][key]
+	if ok && c.clock().Before(entry.expires) {
+		return entry.token, 0, true
+	}
+	if ok {
+		delete(c.entries[id], key)
+		if len(c.entries[id]) == 0 {
+			delete(c.entries, id)
+		}
+	}
+	p, ok := c.pending[id]
+	if !ok {
+		p = &pendingFetches{}
+		c.pending[id] = p
+	}
+	p.count++
+	return "", p.generation, false
+}
+
+// finishFetch returns true if the secret was invalidated since the fetch
+// started, it must be called with the lock held.
+func (c *CachingSecretGetter) finishFetch(id types.NamespacedName, generation uint64) bool {
+	p := c.pending[id]
+	p.count--
+	if p.count == 0 {
+		delete(c.pending, id)
+	}
+	return p.generation != generation
+}
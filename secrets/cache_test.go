@@ -0,0 +1,164 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+)
+
+func TestCachingSecretGetter(t *testing.T) {
+	now := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	stub := NewSecretsStub()
+	stub.StubSecret(testID, "token", "secret-token")
+	g := NewCachingSecretGetter(stub, time.Minute, CacheClock(func() time.Time { return now }))
+	hits, misses := testutil.ToFloat64(cacheHits), testutil.ToFloat64(cacheMisses)
+
+	assertSecretToken(t, g, "secret-token")
+	stub.StubSecret(testID, "token", "new-token")
+	assertSecretToken(t, g, "secret-token")
+
+	now = now.Add(time.Minute)
+	assertSecretToken(t, g, "new-token")
+
+	if v := testutil.ToFloat64(cacheHits) - hits; v != 1 {
+		t.Errorf("got %v cache hits, want 1", v)
+	}
+	if v := testutil.ToFloat64(cacheMisses) - misses; v != 2 {
+		t.Errorf("got %v cache misses, want 2", v)
+	}
+}
+
+func TestRegisterMetrics(t *testing.T) {
+	r := prometheus.NewRegistry()
+	if err := RegisterMetrics(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := testutil.GatherAndCount(r, "gitops_secret_cache_hits_total", "gitops_secret_cache_misses_total"); err != nil || n != 2 {
+		t.Fatalf("got %d metrics, want 2: %v", n, err)
+	}
+	if err := RegisterMetrics(r); err == nil {
+		t.Fatal("expected an error registering the metrics twice")
+	}
+}
+
+func TestCachingSecretGetterDoesNotCacheErrors(t *testing.T) {
+	stub := NewSecretsStub()
+	g := NewCachingSecretGetter(stub, time.Minute)
+
+	if _, err := g.SecretToken(context.TODO(), testID, "token"); err == nil {
+		t.Fatal("expected an error looking up a missing secret")
+	}
+	stub.StubSecret(testID, "token", "secret-token")
+
+	assertSecretToken(t, g, "secret-token")
+}
+
+func TestCachingSecretGetterInvalidateDuringFetch(t *testing.T) {
+	stub := NewSecretsStub()
+	stub.StubSecret(testID, "token", "secret-token")
+	blocking := &blockingSecretGetter{getter: stub, fetching: make(chan struct{}), release: make(chan struct{})}
+	g := NewCachingSecretGetter(blocking, time.Minute)
+
+	done := make(chan string)
+	go func() {
+		token, err := g.SecretToken(context.TODO(), testID, "token")
+		if err != nil {
+			t.Error(err)
+		}
+		done <- token
+	}()
+	<-blocking.fetching
+	stub.StubSecret(testID, "token", "new-token")
+	g.Invalidate(testID)
+	close(blocking.release)
+	if token := <-done; token != "secret-token" {
+		t.Fatalf("got %s, want secret-token", token)
+	}
+
+	assertSecretToken(t, g, "new-token")
+}
+
+func TestCachingSecretGetterInvalidateUncachedSecrets(t *testing.T) {
+	stub := NewSecretsStub()
+	stub.StubSecret(testID, "token", "secret-token")
+	g := NewCachingSecretGetter(stub, time.Minute)
+
+	assertSecretToken(t, g, "secret-token")
+	if _, err := g.SecretToken(context.TODO(), types.NamespacedName{Name: "unknown", Namespace: "test-ns"}, "token"); err == nil {
+		t.Fatal("expected an error looking up a missing secret")
+	}
+	for i := range 10 {
+		g.Invalidate(types.NamespacedName{Name: fmt.Sprintf("secret-%d", i), Namespace: "test-ns"})
+	}
+	g.Invalidate(testID)
+
+	if l := len(g.entries); l != 0 {
+		t.Errorf("got %d cached secrets, want 0", l)
+	}
+	if l := len(g.pending); l != 0 {
+		t.Errorf("got %d secrets with pending fetches, want 0", l)
+	}
+}
+
+func TestCachingSecretGetterWatch(t *testing.T) {
+	stub := NewSecretsStub()
+	stub.StubSecret(testID, "token", "secret-token")
+	g := NewCachingSecretGetter(stub, time.Hour)
+	informer := &controllertest.FakeInformer{}
+	if err := g.Watch(informer); err != nil {
+		t.Fatal(err)
+	}
+	secret := createSecret(testID, "secret-token")
+	secret.ResourceVersion = "1"
+
+	assertSecretToken(t, g, "secret-token")
+	stub.StubSecret(testID, "token", "new-token")
+	informer.Update(secret, secret.DeepCopy())
+	assertSecretToken(t, g, "secret-token")
+
+	updated := secret.DeepCopy()
+	updated.ResourceVersion = "2"
+	informer.Update(secret, updated)
+	assertSecretToken(t, g, "new-token")
+
+	stub.StubSecret(testID, "token", "deleted-token")
+	informer.Delete(updated)
+	assertSecretToken(t, g, "deleted-token")
+}
+
+func assertSecretToken(t *testing.T, g SecretGetter, want string) {
+	t.Helper()
+	token, err := g.SecretToken(context.TODO(), testID, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != want {
+		t.Fatalf("got %s, want %s", token, want)
+	}
+}
+
+// blockingSecretGetter signals when the first token is being fetched, and
+// waits to be released before returning it.
+type blockingSecretGetter struct {
+	getter   SecretGetter
+	fetching chan struct{}
+	release  chan struct{}
+	once     sync.Once
+}
+
+func (g *blockingSecretGetter) SecretToken(ctx context.Context, id types.NamespacedName, key string) (string, error) {
+	token, err := g.getter.SecretToken(ctx, id, key)
+	g.once.Do(func() {
+		close(g.fetching)
+		<-g.release
+	})
+	return token, err
+}
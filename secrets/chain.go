@@ -0,0 +1,42 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ SecretGetter = (*ChainSecretGetter)(nil)
+
+// ChainSecretGetter is an implementation of SecretGetter that tries a list of
+// SecretGetters in order, and returns the first secret that is found.
+type ChainSecretGetter struct {
+	getters []SecretGetter
+}
+
+// NewChainSecretGetter creates and returns a ChainSecretGetter that tries the
+// getters in order.
+func NewChainSecretGetter(getters ...SecretGetter) *ChainSecretGetter {
+	return &ChainSecretGetter{
+		getters: getters,
+	}
+}
+
+// SecretToken tries each of the SecretGetters in order, and returns the
+// first token that is found.
+//
+// If none of the SecretGetters return a token, the error reports the error
+// from each of the SecretGetters that was tried.
+func (c ChainSecretGetter) SecretToken(ctx context.Context, id types.NamespacedName, key string) (string, error) {
+	var errs []error
+	for _, g := range c.getters {
+		token, err := g.SecretToken(ctx, id, key)
+		if err == nil {
+			return token, nil
+		}
+		errs = append(errs, fmt.Errorf("%T: %w", g, err))
+	}
+	return "", fmt.Errorf("failed to get key %q in %s/%s from %d secret getters: %w", key, id.Namespace, id.Name, len(c.getters), errors.Join(errs...))
+}
@@ -0,0 +1,52 @@
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestChainSecretGetter(t *testing.T) {
+	t.Setenv("TEST_TEST_NS_TEST_SECRET_TOKEN", "env-token")
+	g := NewChainSecretGetter(
+		NewEnvSecretGetter("TEST_"),
+		New(fake.NewClientBuilder().WithObjects(createSecret(testID, "secret-token")).Build()))
+
+	secret, err := g.SecretToken(context.TODO(), testID, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if secret != "env-token" {
+		t.Fatalf("got %s, want env-token", secret)
+	}
+}
+
+func TestChainSecretGetterFallsBack(t *testing.T) {
+	g := NewChainSecretGetter(
+		NewEnvSecretGetter("TEST_"),
+		New(fake.NewClientBuilder().WithObjects(createSecret(testID, "secret-token")).Build()))
+
+	secret, err := g.SecretToken(context.TODO(), testID, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if secret != "secret-token" {
+		t.Fatalf("got %s, want secret-token", secret)
+	}
+}
+
+func TestChainSecretGetterWithMissingSecret(t *testing.T) {
+	g := NewChainSecretGetter(
+		NewEnvSecretGetter("TEST_"),
+		New(fake.NewClientBuilder().Build()))
+
+	_, err := g.SecretToken(context.TODO(), testID, "token")
+	want := `failed to get key "token" in test-ns/test-secret from 2 secret getters: *secrets.EnvSecretGetter: environment variable TEST_TEST_NS_TEST_SECRET_TOKEN is not set for key "token" in test-ns/test-secret
+*secrets.KubeSecretGetter: error getting secret test-ns/test-secret: secrets "test-secret" not found`
+	if err.Error() != want {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ SecretGetter = (*EnvSecretGetter)(nil)
+
+// EnvSecretGetter is an implementation of SecretGetter that reads secrets from
+// environment variables.
+//
+// The variable name is the prefix followed by the namespace, name and key,
+// joined with '_', upper cased, and with any other characters that are not
+// valid in variable names replaced with '_', e.g. the key "token" in the secret
+// "my-ns/git-secret" with the prefix "GITOPS_" is read from
+// GITOPS_MY_NS_GIT_SECRET_TOKEN.
+//
+// If the namespaced name is empty, the variable name is the prefix followed by
+// the key, this is used to resolve env secret references.
+type EnvSecretGetter struct {
+	prefix string
+}
+
+// NewEnvSecretGetter creates and returns an EnvSecretGetter that looks up
+// secrets in environment variables with the prefix.
+func NewEnvSecretGetter(prefix string) *EnvSecretGetter {
+	return &EnvSecretGetter{
+		prefix: prefix,
+	}
+}
+
+// SecretToken looks for an environment variable for the namespaced secret
+// and key, and returns the value, or an error if it is not set.
+func (e EnvSecretGetter) SecretToken(ctx context.Context, id types.NamespacedName, key string) (string, error) {
+	name := e.VarName(id, key)
+	token, ok := os.LookupEnv(name)
+	if !ok {
+		if id == (types.NamespacedName{}) {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return "", fmt.Errorf("environment variable %s is not set for key %q in %s/%s", name, key, id.Namespace, id.Name)
+	}
+	return token, nil
+}
+
+// VarName returns the name of the environment variable that the key in the
+// namespaced secret is read from.
+func (e EnvSecretGetter) VarName(id types.NamespacedName, key string) string {
+	return e.prefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		}
+		return '_'
+	}, strings.Join(varNameParts(id, key), "_"))
+}
+
+func varNameParts(id types.NamespacedName, key string) []string {
+	if id == (types.NamespacedName{}) {
+		return []string{key}
+	}
+	return []string{id.Namespace, id.Name, key}
+}
@@ -0,0 +1,54 @@
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEnvSecretGetter(t *testing.T) {
+	t.Setenv("TEST_TEST_NS_TEST_SECRET_TOKEN", "secret-token")
+	g := NewEnvSecretGetter("TEST_")
+
+	secret, err := g.SecretToken(context.TODO(), testID, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if secret != "secret-token" {
+		t.Fatalf("got %s, want secret-token", secret)
+	}
+}
+
+func TestEnvSecretGetterWithMissingVariable(t *testing.T) {
+	g := NewEnvSecretGetter("TEST_")
+
+	_, err := g.SecretToken(context.TODO(), testID, "unknown")
+	if err.Error() != `environment variable TEST_TEST_NS_TEST_SECRET_UNKNOWN is not set for key "unknown" in test-ns/test-secret` {
+		t.Fatal(err)
+	}
+}
+
+func TestEnvSecretGetterVarName(t *testing.T) {
+	g := NewEnvSecretGetter("")
+
+	name := g.VarName(types.NamespacedName{Name: "git.secret", Namespace: "my-ns"}, "ssh-privatekey")
+	if name != "MY_NS_GIT_SECRET_SSH_PRIVATEKEY" {
+		t.Fatalf("got %s, want MY_NS_GIT_SECRET_SSH_PRIVATEKEY", name)
+	}
+}
+
+func TestEnvSecretGetterWithoutNamespacedName(t *testing.T) {
+	t.Setenv("TEST_GITHUB_TOKEN", "secret-token")
+	g := NewEnvSecretGetter("TEST_")
+
+	secret, err := g.SecretToken(context.TODO(), types.NamespacedName{}, "github_token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if secret != "secret-token" {
+		t.Fatalf("got %s, want secret-token", secret)
+	}
+}
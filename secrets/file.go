@@ -0,0 +1,62 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ SecretGetter = (*FileSecretGetter)(nil)
+
+// FileSecretGetter is an implementation of SecretGetter that reads secrets
+// from files, in the layout <dir>/<namespace>/<name>/<key>.
+//
+// This is compatible with mounting each Secret as a volume in a directory for
+// its namespace and name.
+//
+// If the namespaced name is empty, the key is the path of the file relative to
+// the directory, this is used to resolve file secret references.
+type FileSecretGetter struct {
+	dir string
+}
+
+// NewFileSecretGetter creates and returns a FileSecretGetter that looks up
+// secrets in files in the directory.
+func NewFileSecretGetter(dir string) *FileSecretGetter {
+	return &FileSecretGetter{
+		dir: dir,
+	}
+}
+
+// SecretToken reads the file for the namespaced secret and key, and returns
+// the contents, or an error if the file can't be read.
+//
+// Trailing newlines are removed from the contents of the file, as they are
+// almost always added by editors and tools that write the files.
+func (f FileSecretGetter) SecretToken(ctx context.Context, id types.NamespacedName, key string) (string, error) {
+	if id == (types.NamespacedName{}) {
+		if !filepath.IsLocal(key) {
+			return "", fmt.Errorf("invalid path %q, must be relative to the directory", key)
+		}
+		b, err := os.ReadFile(filepath.Join(f.dir, key))
+		if err != nil {
+			return "", fmt.Errorf("error reading secret file: %w", err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+
+	for _, part := range []string{id.Namespace, id.Name, key} {
+		if part == "" || part == "." || part == ".." || strings.ContainsAny(part, `/\`) {
+			return "", fmt.Errorf("invalid path element %q for key %q in %s/%s", part, key, id.Namespace, id.Name)
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(f.dir, id.Namespace, id.Name, key))
+	if err != nil {
+		return "", fmt.Errorf("error reading secret %s/%s: %w", id.Namespace, id.Name, err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
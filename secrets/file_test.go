@@ -0,0 +1,88 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gitops-tools/pkg/test"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestFileSecretGetter(t *testing.T) {
+	dir := t.TempDir()
+	writeSecretFile(t, dir, testID, "token", "secret-token")
+	g := NewFileSecretGetter(dir)
+
+	secret, err := g.SecretToken(context.TODO(), testID, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if secret != "secret-token" {
+		t.Fatalf("got %s, want secret-token", secret)
+	}
+}
+
+func TestFileSecretGetterTrimsNewlines(t *testing.T) {
+	dir := t.TempDir()
+	writeSecretFile(t, dir, testID, "token", "secret-token\n")
+	writeSecretFile(t, dir, testID, "windows-token", "secret-token\r\n")
+	redactor := NewRedactor()
+	g := NewRedactingSecretGetter(NewFileSecretGetter(dir), redactor)
+
+	for _, key := range []string{"token", "windows-token"} {
+		secret, err := g.SecretToken(context.TODO(), testID, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret != "secret-token" {
+			t.Fatalf("got %q, want secret-token", secret)
+		}
+	}
+	if s := redactor.Redact("Authorization: Bearer secret-token"); s != "Authorization: Bearer "+Redacted {
+		t.Fatalf("failed to redact token, got %q", s)
+	}
+}
+
+func TestFileSecretGetterErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeSecretFile(t, dir, testID, "token", "secret-token")
+	g := NewFileSecretGetter(dir)
+
+	errorTests := []struct {
+		name    string
+		id      types.NamespacedName
+		key     string
+		wantErr string
+	}{
+		{"missing key", testID, "unknown", `error reading secret test-ns/test-secret: open .*/test-ns/test-secret/unknown: no such file or directory`},
+		{"missing secret", types.NamespacedName{Name: "unknown", Namespace: "test-ns"}, "token", `error reading secret test-ns/unknown: open .*: no such file or directory`},
+		{"parent directory", types.NamespacedName{Name: "..", Namespace: "test-ns"}, "token", `invalid path element "\.\." for key "token" in test-ns/\.\.`},
+		{"path in key", testID, "../../token", `invalid path element "\.\./\.\./token"`},
+		{"empty namespace", types.NamespacedName{Name: "test-secret"}, "token", `invalid path element "" for key "token" in /test-secret`},
+		{"path outside directory", types.NamespacedName{}, "../token", `invalid path "\.\./token", must be relative to the directory`},
+		{"missing file", types.NamespacedName{}, "unknown", `error reading secret file: open .*/unknown: no such file or directory`},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := g.SecretToken(context.TODO(), tt.id, tt.key)
+			if !test.MatchError(t, tt.wantErr, err) {
+				t.Fatalf("got %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func writeSecretFile(t *testing.T, dir string, id types.NamespacedName, key, value string) {
+	t.Helper()
+	secretDir := filepath.Join(dir, id.Namespace, id.Name)
+	if err := os.MkdirAll(secretDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(secretDir, key), []byte(value), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
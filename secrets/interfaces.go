@@ -11,3 +11,9 @@ import (
 type SecretGetter interface {
 	SecretToken(ctx context.Context, id types.NamespacedName, key string) (string, error)
 }
+
+// SecretSetter creates, updates and rotates the keys in secrets.
+type SecretSetter interface {
+	SetSecret(ctx context.Context, id types.NamespacedName, data map[string]string, opts SetOptions) error
+	RotateSecretKey(ctx context.Context, id types.NamespacedName, oldKey, newKey, value string) (string, error)
+}
@@ -0,0 +1,198 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Redacted replaces secret values in redacted strings.
+const Redacted = "[REDACTED]"
+
+// Redactor records secret values, and replaces them in strings.
+//
+// A Redactor is safe for concurrent use.
+type Redactor struct {
+	mu       sync.RWMutex
+	values   map[string]bool
+	replacer *strings.Replacer
+}
+
+// NewRedactor creates and returns a Redactor with no values.
+func NewRedactor() *Redactor {
+	return &Redactor{
+		values: map[string]bool{},
+	}
+}
+
+// Register adds secret values to be redacted, empty values are ignored.
+func (r *Redactor) Register(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := false
+	for _, v := range values {
+		if v != "" && !r.values[v] {
+			r.values[v] = true
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	// Longer values are replaced first so that values that contain other
+	// values are fully redacted.
+	sorted := make([]string, 0, len(r.values))
+	for v := range r.values {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	pairs := make([]string, 0, len(sorted)*2)
+	for _, v := range sorted {
+		pairs = append(pairs, v, Redacted)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact replaces every occurrence of the registered values in the string.
+func (r *Redactor) Redact(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.replacer == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// RedactLogger wraps the logger so that the values registered with the
+// Redactor are redacted from messages, keys and values and errors.
+//
+// Values that are not strings or errors are formatted with fmt, and if they
+// contain a registered value, are replaced with the redacted string.
+//
+// The verbosity of the logger is preserved.
+func RedactLogger(log logr.Logger, r *Redactor) logr.Logger {
+	sink := log.GetSink()
+	if sink == nil {
+		return log
+	}
+	// The wrapping sink adds a frame between the caller and the wrapped sink.
+	if callDepthSink, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = callDepthSink.WithCallDepth(1)
+	}
+	return log.WithSink(&redactingSink{sink: sink, redactor: r})
+}
+
+var _ logr.CallDepthLogSink = (*redactingSink)(nil)
+
+type redactingSink struct {
+	sink     logr.LogSink
+	redactor *Redactor
+}
+
+// Init is a no-op, the wrapped sink has already been initialised by its
+// logger.
+func (s *redactingSink) Init(info logr.RuntimeInfo) {
+}
+
+func (s *redactingSink) Enabled(level int) bool {
+	return s.sink.Enabled(level)
+}
+
+func (s *redactingSink) Info(level int, msg string, keysAndValues ...any) {
+	s.sink.Info(level, s.redactor.Redact(msg), s.redactValues(keysAndValues)...)
+}
+
+func (s *redactingSink) Error(err error, msg string, keysAndValues ...any) {
+	if err != nil {
+		err = s.redactError(err)
+	}
+	s.sink.Error(err, s.redactor.Redact(msg), s.redactValues(keysAndValues)...)
+}
+
+func (s *redactingSink) WithValues(keysAndValues ...any) logr.LogSink {
+	return &redactingSink{sink: s.sink.WithValues(s.redactValues(keysAndValues)...), redactor: s.redactor}
+}
+
+func (s *redactingSink) WithName(name string) logr.LogSink {
+	return &redactingSink{sink: s.sink.WithName(name), redactor: s.redactor}
+}
+
+func (s *redactingSink) WithCallDepth(depth int) logr.LogSink {
+	if sink, ok := s.sink.(logr.CallDepthLogSink); ok {
+		return &redactingSink{sink: sink.WithCallDepth(depth), redactor: s.redactor}
+	}
+	return s
+}
+
+func (s *redactingSink) redactValues(keysAndValues []any) []any {
+	redacted := make([]any, len(keysAndValues))
+	for i, v := range keysAndValues {
+		redacted[i] = s.redactValue(v)
+	}
+	return redacted
+}
+
+func (s *redactingSink) redactValue(v any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return s.redactor.Redact(v)
+	case []byte:
+		return s.redactor.Redact(string(v))
+	case error:
+		return s.redactError(v)
+	}
+	formatted := fmt.Sprintf("%+v", v)
+	if redacted := s.redactor.Redact(formatted); redacted != formatted {
+		return redacted
+	}
+	return v
+}
+
+// redactError returns the error unchanged if the message contains no secret
+// values, so that the original error type is preserved in the logs.
+func (s *redactingSink) redactError(err error) error {
+	msg := err.Error()
+	if redacted := s.redactor.Redact(msg); redacted != msg {
+		return errors.New(redacted)
+	}
+	return err
+}
+
+var _ SecretGetter = (*RedactingSecretGetter)(nil)
+
+// RedactingSecretGetter is an implementation of SecretGetter that registers
+// the tokens from another SecretGetter with a Redactor.
+type RedactingSecretGetter struct {
+	getter   SecretGetter
+	redactor *Redactor
+}
+
+// NewRedactingSecretGetter creates and returns a RedactingSecretGetter that
+// registers the tokens from the getter with the Redactor.
+func NewRedactingSecretGetter(getter SecretGetter, r *Redactor) *RedactingSecretGetter {
+	return &RedactingSecretGetter{
+		getter:   getter,
+		redactor: r,
+	}
+}
+
+// SecretToken gets the token from the wrapped SecretGetter and registers it
+// with the Redactor.
+func (g RedactingSecretGetter) SecretToken(ctx context.Context, id types.NamespacedName, key string) (string, error) {
+	token, err := g.getter.SecretToken(ctx, id, key)
+	if err != nil {
+		return "", err
+	}
+	g.redactor.Register(token)
+	return token, nil
+}
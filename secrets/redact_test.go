@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor()
+	r.Register("secret", "secret-token", "")
+
+	redacted := r.Redact("using secret-token and secret")
+	if redacted != "using [REDACTED] and [REDACTED]" {
+		t.Fatalf("got %q", redacted)
+	}
+	if v := r.Redact(""); v != "" {
+		t.Fatalf("got %q, want empty string", v)
+	}
+}
+
+func TestRedactLogger(t *testing.T) {
+	var logs []string
+	log := funcr.New(func(prefix, args string) {
+		logs = append(logs, prefix+" "+args)
+	}, funcr.Options{})
+	r := NewRedactor()
+	log = RedactLogger(log, r).WithName("test").WithValues("auth", "Bearer secret-token")
+	r.Register("secret-token")
+
+	log.Info("got token secret-token", "token", "secret-token", "body", []byte("token=secret-token"),
+		"request", struct{ Token string }{"secret-token"}, "count", 1)
+	log.Error(fmt.Errorf("failed to call https://example.com?token=secret-token: %w", errors.New("timeout")),
+		"request failed", "err", errors.New("secret-token is invalid"))
+	log.WithValues("token", "secret-token").Info("late value")
+
+	want := []string{
+		`test "level"=0 "msg"="got token [REDACTED]" "auth"="Bearer secret-token" "token"="[REDACTED]" "body"="token=[REDACTED]" "request"="{Token:[REDACTED]}" "count"=1`,
+		`test "msg"="request failed" "error"="failed to call https://example.com?token=[REDACTED]: timeout" "auth"="Bearer secret-token" "err"="[REDACTED] is invalid"`,
+		`test "level"=0 "msg"="late value" "auth"="Bearer secret-token" "token"="[REDACTED]"`,
+	}
+	if diff := cmp.Diff(want, logs); diff != "" {
+		t.Fatalf("failed to redact logs:\n%s", diff)
+	}
+}
+
+func TestRedactLoggerPreservesVerbosity(t *testing.T) {
+	var logs []string
+	log := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	r := NewRedactor()
+	r.Register("secret-token")
+
+	RedactLogger(log.V(1), r).Info("got token secret-token")
+	RedactLogger(log.V(2), r).Info("debugging secret-token")
+	RedactLogger(log.V(1), r).V(1).Info("debugging secret-token")
+
+	want := []string{`"level"=1 "msg"="got token [REDACTED]"`}
+	if diff := cmp.Diff(want, logs); diff != "" {
+		t.Fatalf("failed to preserve verbosity:\n%s", diff)
+	}
+}
+
+func TestRedactingSecretGetter(t *testing.T) {
+	stub := NewSecretsStub()
+	stub.StubSecret(testID, "token", "secret-token")
+	r := NewRedactor()
+	g := NewRedactingSecretGetter(stub, r)
+
+	assertSecretToken(t, g, "secret-token")
+
+	if v := r.Redact("token secret-token"); v != "token [REDACTED]" {
+		t.Fatalf("got %q, want token [REDACTED]", v)
+	}
+}
+
+func TestKubeSecretGetterRedaction(t *testing.T) {
+	r := NewRedactor()
+	g := New(fake.NewClientBuilder().WithObjects(
+		createTypedSecret(corev1.SecretTypeBasicAuth, map[string]string{"username": "test-user", "password": "test-password"})).Build(),
+		Redaction(r))
+
+	if _, err := g.BasicAuth(context.TODO(), testID); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := r.Redact("test-user:test-password"); v != "test-user:[REDACTED]" {
+		t.Fatalf("got %q, want test-user:[REDACTED]", v)
+	}
+}
@@ -0,0 +1,173 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// The schemes that are supported in secret reference URIs.
+const (
+	KubernetesScheme = "k8s"
+	EnvScheme        = "env"
+	FileScheme       = "file"
+)
+
+// Reference is a parsed secret reference URI.
+//
+//	k8s://namespace/name#key
+//	env://GITOPS_GITHUB_TOKEN
+//	file:///var/run/secrets/gitops/token
+type Reference struct {
+	Scheme string
+	// ID and Key identify the key in a Kubernetes Secret for k8s references.
+	ID  types.NamespacedName
+	Key string
+	// Name is the environment variable for env references, or the absolute path
+	// to the file for file references.
+	Name string
+}
+
+// String returns the reference in URI form.
+func (r Reference) String() string {
+	if r.Scheme == KubernetesScheme {
+		return fmt.Sprintf("%s://%s/%s#%s", r.Scheme, r.ID.Namespace, r.ID.Name, r.Key)
+	}
+	return r.Scheme + "://" + r.Name
+}
+
+// ParseReference parses a secret reference URI.
+func ParseReference(s string) (Reference, error) {
+	scheme, rest, ok := strings.Cut(s, "://")
+	if !ok {
+		return Reference{}, fmt.Errorf("invalid secret reference %q: missing scheme", s)
+	}
+	switch scheme {
+	case KubernetesScheme:
+		path, key, _ := strings.Cut(rest, "#")
+		namespace, name, _ := strings.Cut(path, "/")
+		if namespace == "" || name == "" || key == "" || strings.Contains(name, "/") {
+			return Reference{}, fmt.Errorf("invalid secret reference %q: must be in the form k8s://namespace/name#key", s)
+		}
+		return Reference{Scheme: scheme, ID: types.NamespacedName{Namespace: namespace, Name: name}, Key: key}, nil
+	case EnvScheme:
+		if rest == "" || strings.ContainsAny(rest, "/#=") {
+			return Reference{}, fmt.Errorf("invalid secret reference %q: must be in the form env://NAME", s)
+		}
+		return Reference{Scheme: scheme, Name: rest}, nil
+	case FileScheme:
+		if !filepath.IsAbs(rest) {
+			return Reference{}, fmt.Errorf("invalid secret reference %q: must be in the form file:///absolute/path", s)
+		}
+		return Reference{Scheme: scheme, Name: filepath.Clean(rest)}, nil
+	}
+	return Reference{}, fmt.Errorf("invalid secret reference %q: unsupported scheme %q", s, scheme)
+}
+
+// Resolver resolves secret reference URIs with a SecretGetter for each scheme.
+//
+// Env and file references are rejected unless they are enabled with the
+// EnvReferences and FileReferences options.
+type Resolver struct {
+	getter            SecretGetter
+	allowedNamespaces sets.Set[string]
+	envGetter         SecretGetter
+	envPrefix         string
+	fileGetter        SecretGetter
+	fileDir           string
+}
+
+// ResolverOption is an option func for the Resolver creation function.
+type ResolverOption func(r *Resolver)
+
+// AllowedNamespaces is an option func for the Resolver creation function that
+// restricts the namespaces that k8s references can point to.
+//
+// If no namespaces are provided, references to any namespace are resolved.
+func AllowedNamespaces(namespaces ...string) ResolverOption {
+	return func(r *Resolver) {
+		r.allowedNamespaces = sets.New(namespaces...)
+	}
+}
+
+// EnvReferences is an option func for the Resolver creation function that
+// resolves env references to variables that start with the prefix.
+//
+// The variables are read from the getter with an empty namespaced name and the
+// variable name as the key, e.g. an EnvSecretGetter with no prefix, which can
+// be wrapped in a RedactingSecretGetter.
+//
+// An empty prefix allows references to every variable.
+func EnvReferences(getter SecretGetter, prefix string) ResolverOption {
+	return func(r *Resolver) {
+		r.envGetter = getter
+		r.envPrefix = prefix
+	}
+}
+
+// FileReferences is an option func for the Resolver creation function that
+// resolves file references to files in the directory.
+//
+// The files are read from the getter with an empty namespaced name and the
+// path relative to the directory as the key, e.g. a FileSecretGetter for the
+// directory, which can be wrapped in a RedactingSecretGetter.
+func FileReferences(getter SecretGetter, dir string) ResolverOption {
+	return func(r *Resolver) {
+		r.fileGetter = getter
+		r.fileDir = filepath.Clean(dir)
+	}
+}
+
+// NewResolver creates and returns a Resolver that uses the getter to resolve
+// k8s references.
+func NewResolver(getter SecretGetter, opts ...ResolverOption) *Resolver {
+	r := &Resolver{
+		getter: getter,
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// Resolve parses a secret reference URI and returns the secret it points to.
+func (r Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	parsed, err := ParseReference(ref)
+	if err != nil {
+		return "", err
+	}
+	return r.ResolveReference(ctx, parsed)
+}
+
+// ResolveReference returns the secret that the reference points to.
+func (r Resolver) ResolveReference(ctx context.Context, ref Reference) (string, error) {
+	switch ref.Scheme {
+	case KubernetesScheme:
+		if r.allowedNamespaces.Len() > 0 && !r.allowedNamespaces.Has(ref.ID.Namespace) {
+			return "", fmt.Errorf("secret reference %s is not in an allowed namespace", ref)
+		}
+		return r.getter.SecretToken(ctx, ref.ID, ref.Key)
+	case EnvScheme:
+		if r.envGetter == nil {
+			return "", fmt.Errorf("secret reference %s is not allowed, env references are not enabled", ref)
+		}
+		if !strings.HasPrefix(ref.Name, r.envPrefix) {
+			return "", fmt.Errorf("secret reference %s is not allowed, the variable must start with %q", ref, r.envPrefix)
+		}
+		return r.envGetter.SecretToken(ctx, types.NamespacedName{}, ref.Name)
+	case FileScheme:
+		if r.fileGetter == nil {
+			return "", fmt.Errorf("secret reference %s is not allowed, file references are not enabled", ref)
+		}
+		rel, err := filepath.Rel(r.fileDir, ref.Name)
+		if err != nil || !filepath.IsLocal(rel) {
+			return "", fmt.Errorf("secret reference %s is not allowed, the file must be in %s", ref, r.fileDir)
+		}
+		return r.fileGetter.SecretToken(ctx, types.NamespacedName{}, rel)
+	}
+	return "", fmt.Errorf("unsupported secret reference scheme %q", ref.Scheme)
+}
@@ -0,0 +1,149 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gitops-tools/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestParseReference(t *testing.T) {
+	parseTests := []struct {
+		ref     string
+		want    Reference
+		wantErr string
+	}{
+		{ref: "k8s://test-ns/test-secret#token", want: Reference{Scheme: KubernetesScheme, ID: testID, Key: "token"}},
+		{ref: "env://GITHUB_TOKEN", want: Reference{Scheme: EnvScheme, Name: "GITHUB_TOKEN"}},
+		{ref: "file:///var/run/secrets/../secrets/token", want: Reference{Scheme: FileScheme, Name: "/var/run/secrets/token"}},
+		{ref: "test-ns/test-secret", wantErr: `invalid secret reference "test-ns/test-secret": missing scheme`},
+		{ref: "vault://secret/token", wantErr: `unsupported scheme "vault"`},
+		{ref: "k8s://test-ns/test-secret", wantErr: `must be in the form k8s://namespace/name#key`},
+		{ref: "k8s://test-secret#token", wantErr: `must be in the form k8s://namespace/name#key`},
+		{ref: "k8s://test-ns/test/secret#token", wantErr: `must be in the form k8s://namespace/name#key`},
+		{ref: "env://", wantErr: `must be in the form env://NAME`},
+		{ref: "file://token", wantErr: `must be in the form file:///absolute/path`},
+	}
+
+	for _, tt := range parseTests {
+		t.Run(tt.ref, func(t *testing.T) {
+			ref, err := ParseReference(tt.ref)
+			if !test.MatchError(t, tt.wantErr, err) {
+				t.Fatalf("got %v, want %s", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, ref); diff != "" {
+				t.Fatalf("failed to parse reference:\n%s", diff)
+			}
+			if tt.wantErr == "" && ref.String() != tt.ref && tt.want.Scheme != FileScheme {
+				t.Fatalf("got %s, want %s", ref.String(), tt.ref)
+			}
+		})
+	}
+}
+
+func TestResolver(t *testing.T) {
+	t.Setenv("TEST_GITHUB_TOKEN", "env-token")
+	t.Setenv("OTHER_TOKEN", "other-token")
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stub := NewSecretsStub()
+	stub.StubSecret(testID, "token", "secret-token")
+	stub.StubSecret(types.NamespacedName{Name: "test-secret", Namespace: "other-ns"}, "token", "other-token")
+	r := NewResolver(stub, AllowedNamespaces("test-ns"),
+		EnvReferences(NewEnvSecretGetter(""), "TEST_"),
+		FileReferences(NewFileSecretGetter(dir), dir))
+
+	resolveTests := []struct {
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{ref: "k8s://test-ns/test-secret#token", want: "secret-token"},
+		{ref: "env://TEST_GITHUB_TOKEN", want: "env-token"},
+		{ref: "file://" + tokenFile, want: "file-token"},
+		{ref: "k8s://other-ns/test-secret#token", wantErr: `secret reference k8s://other-ns/test-secret#token is not in an allowed namespace`},
+		{ref: "k8s://test-ns/test-secret#unknown", wantErr: `not found`},
+		{ref: "env://TEST_UNKNOWN", wantErr: `environment variable TEST_UNKNOWN is not set`},
+		{ref: "env://OTHER_TOKEN", wantErr: `secret reference env://OTHER_TOKEN is not allowed, the variable must start with "TEST_"`},
+		{ref: "file://" + filepath.Join(dir, "unknown"), wantErr: `error reading secret file: open .*/unknown: no such file or directory`},
+		{ref: "file:///var/run/secrets/token", wantErr: `secret reference file:///var/run/secrets/token is not allowed, the file must be in .*`},
+		{ref: "file://" + dir + "/../token", wantErr: `is not allowed, the file must be in`},
+		{ref: "unknown", wantErr: `invalid secret reference "unknown": missing scheme`},
+	}
+
+	for _, tt := range resolveTests {
+		t.Run(tt.ref, func(t *testing.T) {
+			token, err := r.Resolve(context.TODO(), tt.ref)
+			if !test.MatchError(t, tt.wantErr, err) {
+				t.Fatalf("got %v, want %s", err, tt.wantErr)
+			}
+			if token != tt.want {
+				t.Fatalf("got %s, want %s", token, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolverRejectsEnvAndFileReferences(t *testing.T) {
+	t.Setenv("TEST_GITHUB_TOKEN", "env-token")
+	r := NewResolver(NewSecretsStub())
+
+	resolveTests := []struct {
+		ref     string
+		wantErr string
+	}{
+		{ref: "env://TEST_GITHUB_TOKEN", wantErr: `secret reference env://TEST_GITHUB_TOKEN is not allowed, env references are not enabled`},
+		{ref: "file:///var/run/secrets/token", wantErr: `secret reference file:///var/run/secrets/token is not allowed, file references are not enabled`},
+	}
+
+	for _, tt := range resolveTests {
+		t.Run(tt.ref, func(t *testing.T) {
+			_, err := r.Resolve(context.TODO(), tt.ref)
+			if !test.MatchError(t, tt.wantErr, err) {
+				t.Fatalf("got %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolverRedactsEnvAndFileReferences(t *testing.T) {
+	t.Setenv("TEST_GITHUB_TOKEN", "env-token")
+	dir := t.TempDir()
+	writeSecretFile(t, dir, testID, "token", "file-token")
+	redactor := NewRedactor()
+	r := NewResolver(NewSecretsStub(),
+		EnvReferences(NewRedactingSecretGetter(NewEnvSecretGetter(""), redactor), "TEST_"),
+		FileReferences(NewRedactingSecretGetter(NewFileSecretGetter(dir), redactor), dir))
+
+	for _, ref := range []string{"env://TEST_GITHUB_TOKEN", "file://" + filepath.Join(dir, "test-ns/test-secret/token")} {
+		if _, err := r.Resolve(context.TODO(), ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := "tokens " + Redacted + " " + Redacted
+	if s := redactor.Redact("tokens env-token file-token"); s != want {
+		t.Fatalf("got %q, want %q", s, want)
+	}
+}
+
+func TestResolverWithoutAllowedNamespaces(t *testing.T) {
+	stub := NewSecretsStub()
+	stub.StubSecret(testID, "token", "secret-token")
+	r := NewResolver(stub)
+
+	token, err := r.Resolve(context.TODO(), "k8s://test-ns/test-secret#token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "secret-token" {
+		t.Fatalf("got %s, want secret-token", token)
+	}
+}
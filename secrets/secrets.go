@@ -12,25 +12,59 @@ import (
 // KubeSecretGetter is an implementation of SecretGetter.
 type KubeSecretGetter struct {
 	kubeClient client.Client
+	redactor   *Redactor
+}
+
+// Option is an option func for the KubeSecretGetter creation function.
+type Option func(k *KubeSecretGetter)
+
+// Redaction is an option func for the KubeSecretGetter creation function that
+// registers the secret values that are returned with the Redactor.
+//
+// For the typed accessors only the credentials are registered, e.g. the
+// password but not the username for basic-auth secrets.
+func Redaction(r *Redactor) Option {
+	return func(k *KubeSecretGetter) {
+		k.redactor = r
+	}
 }
 
 // New creates and returns a KubeSecretGetter that looks up secrets in k8s.
-func New(c client.Client) *KubeSecretGetter {
-	return &KubeSecretGetter{
+func New(c client.Client, opts ...Option) *KubeSecretGetter {
+	k := &KubeSecretGetter{
 		kubeClient: c,
 	}
+	for _, o := range opts {
+		o(k)
+	}
+	return k
 }
 
 // SecretToken looks for a namespaced secret, and returns the key from
 // it, or an error if not found.
 func (k KubeSecretGetter) SecretToken(ctx context.Context, id types.NamespacedName, key string) (string, error) {
+	loaded, err := k.getSecret(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	token, err := secretKey(loaded, key)
+	if err != nil {
+		return "", err
+	}
+	k.register(string(token))
+	return string(token), nil
+}
+
+func (k KubeSecretGetter) getSecret(ctx context.Context, id types.NamespacedName) (*corev1.Secret, error) {
 	loaded := &corev1.Secret{}
 	if err := k.kubeClient.Get(ctx, id, loaded); err != nil {
-		return "", fmt.Errorf("error getting secret %s/%s: %w", id.Namespace, id.Name, err)
+		return nil, fmt.Errorf("error getting secret %s/%s: %w", id.Namespace, id.Name, err)
 	}
-	token, ok := loaded.Data[key]
-	if !ok {
-		return "", fmt.Errorf("secret invalid, no %q key in %s/%s", key, id.Namespace, id.Name)
+	return loaded, nil
+}
+
+func (k KubeSecretGetter) register(values ...string) {
+	if k.redactor != nil {
+		k.redactor.Register(values...)
 	}
-	return string(token), nil
 }
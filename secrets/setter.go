@@ -0,0 +1,142 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultFieldManager is the field manager that is used when applying secrets.
+const DefaultFieldManager = "gitops-tools"
+
+var _ SecretSetter = (*KubeSecretSetter)(nil)
+
+// SetOptions configures the Secret that is written by a SecretSetter.
+type SetOptions struct {
+	// Type defaults to Opaque.
+	Type            corev1.SecretType
+	Labels          map[string]string
+	Annotations     map[string]string
+	OwnerReferences []metav1.OwnerReference
+}
+
+// KubeSecretSetter is an implementation of SecretSetter that writes secrets
+// to k8s.
+type KubeSecretSetter struct {
+	kubeClient   client.Client
+	fieldManager string
+}
+
+// SetterOption is an option func for the KubeSecretSetter creation function.
+type SetterOption func(k *KubeSecretSetter)
+
+// FieldManager is an option func for the KubeSecretSetter creation function
+// that replaces the DefaultFieldManager used when applying secrets.
+func FieldManager(name string) SetterOption {
+	return func(k *KubeSecretSetter) {
+		k.fieldManager = name
+	}
+}
+
+// NewKubeSecretSetter creates and returns a KubeSecretSetter that writes
+// secrets to k8s.
+func NewKubeSecretSetter(c client.Client, opts ...SetterOption) *KubeSecretSetter {
+	k := &KubeSecretSetter{
+		kubeClient:   c,
+		fieldManager: DefaultFieldManager,
+	}
+	for _, o := range opts {
+		o(k)
+	}
+	return k
+}
+
+// SetSecret creates or updates a namespaced secret with server-side apply.
+//
+// The data, labels, annotations and owner references replace any that were previously
+// applied by the same field manager, keys that were written by other field
+// managers are left unchanged.
+func (k KubeSecretSetter) SetSecret(ctx context.Context, id types.NamespacedName, data map[string]string, opts SetOptions) error {
+	secretType := opts.Type
+	if secretType == "" {
+		secretType = corev1.SecretTypeOpaque
+	}
+	secretData := make(map[string][]byte, len(data))
+	for k, v := range data {
+		secretData[k] = []byte(v)
+	}
+	secret := corev1ac.Secret(id.Name, id.Namespace).
+		WithType(secretType).
+		WithData(secretData).
+		WithLabels(opts.Labels).
+		WithAnnotations(opts.Annotations)
+	for _, ref := range opts.OwnerReferences {
+		ownerRef := metav1ac.OwnerReference().
+			WithAPIVersion(ref.APIVersion).
+			WithKind(ref.Kind).
+			WithName(ref.Name).
+			WithUID(ref.UID)
+		if ref.Controller != nil {
+			ownerRef.WithController(*ref.Controller)
+		}
+		if ref.BlockOwnerDeletion != nil {
+			ownerRef.WithBlockOwnerDeletion(*ref.BlockOwnerDeletion)
+		}
+		secret.WithOwnerReferences(ownerRef)
+	}
+
+	if err := k.kubeClient.Apply(ctx, secret, client.FieldOwner(k.fieldManager), client.ForceOwnership); err != nil {
+		return fmt.Errorf("error applying secret %s/%s: %w", id.Namespace, id.Name, err)
+	}
+	return nil
+}
+
+// RotateSecretKey replaces the old key in a namespaced secret with a new key,
+// and returns the previous value of the old key, or an empty string if the old
+// key was not set.
+//
+// The new key is written first, and the old key is removed in a separate
+// update, so that readers can switch to the new key before the old key is
+// removed. Each update is retried if the secret was changed concurrently.
+//
+// The previous value is read in the same update that writes the new key, so
+// callers can continue to accept it for a grace period after the rotation.
+func (k KubeSecretSetter) RotateSecretKey(ctx context.Context, id types.NamespacedName, oldKey, newKey, value string) (string, error) {
+	var previous string
+	err := k.updateSecret(ctx, id, func(s *corev1.Secret) {
+		previous = string(s.Data[oldKey])
+		if s.Data == nil {
+			s.Data = map[string][]byte{}
+		}
+		s.Data[newKey] = []byte(value)
+	})
+	if err != nil {
+		return "", fmt.Errorf("error writing key %q to secret %s/%s: %w", newKey, id.Namespace, id.Name, err)
+	}
+	if oldKey == newKey {
+		return previous, nil
+	}
+
+	if err := k.updateSecret(ctx, id, func(s *corev1.Secret) { delete(s.Data, oldKey) }); err != nil {
+		return "", fmt.Errorf("error removing key %q from secret %s/%s: %w", oldKey, id.Namespace, id.Name, err)
+	}
+	return previous, nil
+}
+
+func (k KubeSecretSetter) updateSecret(ctx context.Context, id types.NamespacedName, update func(*corev1.Secret)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret := &corev1.Secret{}
+		if err := k.kubeClient.Get(ctx, id, secret); err != nil {
+			return err
+		}
+		update(secret)
+		return k.kubeClient.Update(ctx, secret, client.FieldOwner(k.fieldManager))
+	})
+}
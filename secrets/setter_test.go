@@ -0,0 +1,147 @@
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gitops-tools/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ SecretSetter = (*KubeSecretSetter)(nil)
+
+func TestSetSecret(t *testing.T) {
+	cl := fake.NewClientBuilder().Build()
+	s := NewKubeSecretSetter(cl)
+	owner := metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "test-owner", UID: "test-uid", Controller: ptr.To(true)}
+
+	err := s.SetSecret(context.TODO(), testID, map[string]string{"token": "secret-token"}, SetOptions{
+		Labels:          map[string]string{"app.kubernetes.io/managed-by": "test"},
+		Annotations:     map[string]string{"example.com/note": "test"},
+		OwnerReferences: []metav1.OwnerReference{owner},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := &corev1.Secret{}
+	if err := cl.Get(context.TODO(), testID, secret); err != nil {
+		t.Fatal(err)
+	}
+	if secret.Type != corev1.SecretTypeOpaque {
+		t.Errorf("got type %q, want %q", secret.Type, corev1.SecretTypeOpaque)
+	}
+	if diff := cmp.Diff(map[string]string{"app.kubernetes.io/managed-by": "test"}, secret.Labels); diff != "" {
+		t.Errorf("failed to set labels:\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{"example.com/note": "test"}, secret.Annotations); diff != "" {
+		t.Errorf("failed to set annotations:\n%s", diff)
+	}
+	if diff := cmp.Diff([]metav1.OwnerReference{owner}, secret.OwnerReferences); diff != "" {
+		t.Errorf("failed to set owner references:\n%s", diff)
+	}
+	assertSecretToken(t, New(cl), "secret-token")
+}
+
+func TestSetSecretUpdatesExistingSecret(t *testing.T) {
+	cl := fake.NewClientBuilder().Build()
+	s := NewKubeSecretSetter(cl)
+
+	if err := s.SetSecret(context.TODO(), testID, map[string]string{"token": "secret-token"}, SetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetSecret(context.TODO(), testID, map[string]string{"token": "new-token"}, SetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertSecretToken(t, New(cl), "new-token")
+}
+
+func TestRotateSecretKey(t *testing.T) {
+	secret := createSecret(testID, "secret-token")
+	conflicts := 1
+	var updates []map[string][]byte
+	cl := fake.NewClientBuilder().WithObjects(secret).WithInterceptorFuncs(interceptor.Funcs{
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			if conflicts > 0 {
+				conflicts--
+				return apierrors.NewConflict(schema.GroupResource{Resource: "secrets"}, obj.GetName(), nil)
+			}
+			updates = append(updates, obj.(*corev1.Secret).DeepCopy().Data)
+			return c.Update(ctx, obj, opts...)
+		},
+	}).Build()
+	s := NewKubeSecretSetter(cl)
+
+	previous, err := s.RotateSecretKey(context.TODO(), testID, "token", "token-v2", "new-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != "secret-token" {
+		t.Errorf("got previous value %q, want secret-token", previous)
+	}
+
+	want := []map[string][]byte{
+		{"token": []byte("secret-token"), "token-v2": []byte("new-token")},
+		{"token-v2": []byte("new-token")},
+	}
+	if diff := cmp.Diff(want, updates); diff != "" {
+		t.Fatalf("failed to rotate secret:\n%s", diff)
+	}
+}
+
+func TestRotateSecretKeyWithSameKey(t *testing.T) {
+	cl := fake.NewClientBuilder().WithObjects(createSecret(testID, "secret-token")).Build()
+	s := NewKubeSecretSetter(cl)
+
+	previous, err := s.RotateSecretKey(context.TODO(), testID, "token", "token", "new-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if previous != "secret-token" {
+		t.Errorf("got previous value %q, want secret-token", previous)
+	}
+	assertSecretToken(t, New(cl), "new-token")
+}
+
+func TestRotateSecretKeyWithMissingSecret(t *testing.T) {
+	s := NewKubeSecretSetter(fake.NewClientBuilder().Build())
+
+	_, err := s.RotateSecretKey(context.TODO(), testID, "token", "token-v2", "new-token")
+	if !test.MatchError(t, `error writing key "token-v2" to secret test-ns/test-secret: secrets "test-secret" not found`, err) {
+		t.Fatal(err)
+	}
+}
+
+func TestSecretsStubSetter(t *testing.T) {
+	stub := NewSecretsStub()
+	opts := SetOptions{Labels: map[string]string{"test": "label"}}
+
+	if err := stub.SetSecret(context.TODO(), testID, map[string]string{"old-token": "secret-token"}, opts); err != nil {
+		t.Fatal(err)
+	}
+	previous, err := stub.RotateSecretKey(context.TODO(), testID, "old-token", "token", "new-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != "secret-token" {
+		t.Errorf("got previous value %q, want secret-token", previous)
+	}
+
+	assertSecretToken(t, stub, "new-token")
+	if _, err := stub.SecretToken(context.TODO(), testID, "old-token"); err == nil {
+		t.Fatal("expected the old key to be removed")
+	}
+	if diff := cmp.Diff(opts, stub.SetOptions(testID)); diff != "" {
+		t.Fatalf("failed to record options:\n%s", diff)
+	}
+}
@@ -9,18 +9,22 @@ import (
 )
 
 var _ SecretGetter = (*SecretsStub)(nil)
+var _ SecretSetter = (*SecretsStub)(nil)
 
 // NewSecretsStub creates and returns a new SecretsStub.
 func NewSecretsStub() *SecretsStub {
 	return &SecretsStub{
 		secrets: map[string]string{},
+		options: map[types.NamespacedName]SetOptions{},
 	}
 }
 
 // SecretsStub is an implementation of the SecretGetter interface.
 type SecretsStub struct {
 	tokenErr error
+	setErr   error
 	secrets  map[string]string
+	options  map[types.NamespacedName]SetOptions
 }
 
 // SecretToken is an implementation of the SecretGetter interface.
@@ -42,6 +46,44 @@ func (s *SecretsStub) StubError(err error) {
 	s.tokenErr = err
 }
 
+// SetSecret is an implementation of the SecretSetter interface.
+//
+// The data is stored so that it is returned from SecretToken.
+func (s *SecretsStub) SetSecret(ctx context.Context, id types.NamespacedName, data map[string]string, opts SetOptions) error {
+	if s.setErr != nil {
+		return s.setErr
+	}
+	for k, v := range data {
+		s.secrets[stubKey(id, k)] = v
+	}
+	s.options[id] = opts
+	return nil
+}
+
+// RotateSecretKey is an implementation of the SecretSetter interface.
+func (s *SecretsStub) RotateSecretKey(ctx context.Context, id types.NamespacedName, oldKey, newKey, value string) (string, error) {
+	if s.setErr != nil {
+		return "", s.setErr
+	}
+	previous := s.secrets[stubKey(id, oldKey)]
+	s.secrets[stubKey(id, newKey)] = value
+	if oldKey != newKey {
+		delete(s.secrets, stubKey(id, oldKey))
+	}
+	return previous, nil
+}
+
+// StubSetError stubs the return value of SetSecret and RotateSecretKey as an
+// error.
+func (s *SecretsStub) StubSetError(err error) {
+	s.setErr = err
+}
+
+// SetOptions returns the options that the secret was last set with.
+func (s *SecretsStub) SetOptions(id types.NamespacedName) SetOptions {
+	return s.options[id]
+}
+
 func stubKey(id types.NamespacedName, key string) string {
 	return strings.Join([]string{id.Name, id.Namespace, key}, ":")
 }
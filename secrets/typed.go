@@ -0,0 +1,322 @@
+package secrets
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// KnownHostsKey is the key in an SSH auth Secret that contains the known
+// hosts in the OpenSSH known_hosts format.
+const KnownHostsKey = "known_hosts"
+
+// CACertKey is the key in a Secret that contains a PEM encoded CA bundle.
+const CACertKey = "ca.crt"
+
+// BasicAuth is the username and password from a kubernetes.io/basic-auth
+// Secret.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// SSHAuth is the private key and known hosts from a kubernetes.io/ssh-auth
+// Secret.
+type SSHAuth struct {
+	PrivateKey []byte
+	KnownHosts []byte
+	Signer     ssh.Signer // parsed from the PrivateKey
+}
+
+// RegistryCredentials are the credentials for a container registry from a
+// Docker config Secret.
+type RegistryCredentials struct {
+	Username string
+	Password string
+}
+
+// DockerConfig maps container registry hosts to credentials.
+type DockerConfig map[string]RegistryCredentials
+
+// ForHost returns the credentials for a registry host.
+//
+// The host can be provided as a URL e.g. https://index.docker.io/v1/, and is
+// matched against the hosts in the config in the same way.
+func (d DockerConfig) ForHost(host string) (RegistryCredentials, bool) {
+	creds, ok := d[registryHost(host)]
+	return creds, ok
+}
+
+// CABundle is a PEM encoded bundle of CA certificates.
+type CABundle struct {
+	PEM          []byte
+	Certificates []*x509.Certificate
+}
+
+// CertPool returns a pool containing the certificates in the bundle.
+func (c CABundle) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, cert := range c.Certificates {
+		pool.AddCert(cert)
+	}
+	return pool
+}
+
+// BasicAuth looks for a namespaced kubernetes.io/basic-auth secret and returns
+// the username and password.
+func (k KubeSecretGetter) BasicAuth(ctx context.Context, id types.NamespacedName) (*BasicAuth, error) {
+	secret, err := k.getSecret(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := ParseBasicAuth(secret)
+	if err != nil {
+		return nil, err
+	}
+	k.register(auth.Password)
+	return auth, nil
+}
+
+// SSHAuth looks for a namespaced kubernetes.io/ssh-auth secret and returns the
+// private key and known hosts.
+func (k KubeSecretGetter) SSHAuth(ctx context.Context, id types.NamespacedName) (*SSHAuth, error) {
+	secret, err := k.getSecret(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := ParseSSHAuth(secret)
+	if err != nil {
+		return nil, err
+	}
+	k.register(string(auth.PrivateKey))
+	return auth, nil
+}
+
+// DockerConfig looks for a namespaced kubernetes.io/dockerconfigjson or
+// kubernetes.io/dockercfg secret and returns the registry credentials.
+func (k KubeSecretGetter) DockerConfig(ctx context.Context, id types.NamespacedName) (DockerConfig, error) {
+	secret, err := k.getSecret(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseDockerConfig(secret)
+	if err != nil {
+		return nil, err
+	}
+	for _, creds := range config {
+		k.register(creds.Password)
+	}
+	return config, nil
+}
+
+// CABundle looks for a namespaced Opaque or kubernetes.io/tls secret and
+// returns the CA bundle from the ca.crt key.
+func (k KubeSecretGetter) CABundle(ctx context.Context, id types.NamespacedName) (*CABundle, error) {
+	secret, err := k.getSecret(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCABundle(secret)
+}
+
+// ParseBasicAuth parses the username and password from a
+// kubernetes.io/basic-auth Secret.
+func ParseBasicAuth(secret *corev1.Secret) (*BasicAuth, error) {
+	if err := checkSecretType(secret, corev1.SecretTypeBasicAuth); err != nil {
+		return nil, err
+	}
+	username, err := secretKey(secret, corev1.BasicAuthUsernameKey)
+	if err != nil {
+		return nil, err
+	}
+	password, err := secretKey(secret, corev1.BasicAuthPasswordKey)
+	if err != nil {
+		return nil, err
+	}
+	return &BasicAuth{Username: string(username), Password: string(password)}, nil
+}
+
+// ParseSSHAuth parses the private key and known hosts from a
+// kubernetes.io/ssh-auth Secret.
+//
+// The private key must not be protected by a passphrase.
+func ParseSSHAuth(secret *corev1.Secret) (*SSHAuth, error) {
+	if err := checkSecretType(secret, corev1.SecretTypeSSHAuth); err != nil {
+		return nil, err
+	}
+	privateKey, err := secretKey(secret, corev1.SSHAuthPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s in %s/%s: %w", corev1.SSHAuthPrivateKey, secret.Namespace, secret.Name, err)
+	}
+	knownHosts, err := secretKey(secret, KnownHostsKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateKnownHosts(knownHosts); err != nil {
+		return nil, fmt.Errorf("failed to parse %s in %s/%s: %w", KnownHostsKey, secret.Namespace, secret.Name, err)
+	}
+	return &SSHAuth{PrivateKey: privateKey, KnownHosts: knownHosts, Signer: signer}, nil
+}
+
+// ParseDockerConfig parses the registry credentials from a
+// kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg Secret.
+func ParseDockerConfig(secret *corev1.Secret) (DockerConfig, error) {
+	if err := checkSecretType(secret, corev1.SecretTypeDockerConfigJson, corev1.SecretTypeDockercfg); err != nil {
+		return nil, err
+	}
+	var auths map[string]dockerAuth
+	if secret.Type == corev1.SecretTypeDockercfg {
+		b, err := secretKey(secret, corev1.DockerConfigKey)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &auths); err != nil {
+			return nil, fmt.Errorf("failed to parse %s in %s/%s: %w", corev1.DockerConfigKey, secret.Namespace, secret.Name, err)
+		}
+	} else {
+		b, err := secretKey(secret, corev1.DockerConfigJsonKey)
+		if err != nil {
+			return nil, err
+		}
+		var config struct {
+			Auths map[string]dockerAuth `json:"auths"`
+		}
+		if err := json.Unmarshal(b, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse %s in %s/%s: %w", corev1.DockerConfigJsonKey, secret.Namespace, secret.Name, err)
+		}
+		auths = config.Auths
+	}
+
+	config := DockerConfig{}
+	for host, auth := range auths {
+		creds, err := auth.credentials()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse credentials for %s in %s/%s: %w", host, secret.Namespace, secret.Name, err)
+		}
+		config[registryHost(host)] = creds
+	}
+	return config, nil
+}
+
+// ParseCABundle parses the PEM encoded certificates from the ca.crt key in an
+// Opaque or kubernetes.io/tls Secret.
+func ParseCABundle(secret *corev1.Secret) (*CABundle, error) {
+	if err := checkSecretType(secret, corev1.SecretTypeOpaque, corev1.SecretTypeTLS); err != nil {
+		return nil, err
+	}
+	b, err := secretKey(secret, CACertKey)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &CABundle{PEM: b}
+	for rest := b; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s in %s/%s: %w", CACertKey, secret.Namespace, secret.Name, err)
+		}
+		bundle.Certificates = append(bundle.Certificates, cert)
+	}
+	if len(bundle.Certificates) == 0 {
+		return nil, fmt.Errorf("no certificates found in %s in %s/%s", CACertKey, secret.Namespace, secret.Name)
+	}
+	return bundle, nil
+}
+
+type dockerAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+func (a dockerAuth) credentials() (RegistryCredentials, error) {
+	if a.Auth == "" {
+		return RegistryCredentials{Username: a.Username, Password: a.Password}, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+	if err != nil {
+		return RegistryCredentials{}, fmt.Errorf("failed to decode auth: %w", err)
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return RegistryCredentials{}, errors.New("auth is not in the form username:password")
+	}
+	return RegistryCredentials{Username: username, Password: password}, nil
+}
+
+// registryHost returns the host from a registry that can be a URL.
+func registryHost(s string) string {
+	if strings.Contains(s, "://") {
+		if u, err := url.Parse(s); err == nil {
+			return u.Host
+		}
+	}
+	host, _, _ := strings.Cut(s, "/")
+	return host
+}
+
+func validateKnownHosts(b []byte) error {
+	hosts := 0
+	for rest := b; len(rest) > 0; hosts++ {
+		var err error
+		_, _, _, _, rest, err = ssh.ParseKnownHosts(rest)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if hosts == 0 {
+		return errors.New("no host keys found")
+	}
+	return nil
+}
+
+func checkSecretType(secret *corev1.Secret, types ...corev1.SecretType) error {
+	secretType := secret.Type
+	if secretType == "" {
+		secretType = corev1.SecretTypeOpaque
+	}
+	for _, t := range types {
+		if secretType == t {
+			return nil
+		}
+	}
+	want := make([]string, len(types))
+	for i, t := range types {
+		want[i] = fmt.Sprintf("%q", t)
+	}
+	return fmt.Errorf("secret %s/%s has type %q, want %s", secret.Namespace, secret.Name, secret.Type, strings.Join(want, " or "))
+}
+
+func secretKey(secret *corev1.Secret, key string) ([]byte, error) {
+	b, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("secret invalid, no %q key in %s/%s", key, secret.Namespace, secret.Name)
+	}
+	return b, nil
+}
@@ -0,0 +1,242 @@
+package secrets
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/gitops-tools/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestBasicAuth(t *testing.T) {
+	secret := createTypedSecret(corev1.SecretTypeBasicAuth, map[string]string{"username": "test-user", "password": "test-password"})
+	g := New(fake.NewClientBuilder().WithObjects(secret).Build())
+
+	auth, err := g.BasicAuth(context.TODO(), testID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(&BasicAuth{Username: "test-user", Password: "test-password"}, auth); diff != "" {
+		t.Fatalf("failed to get basic auth:\n%s", diff)
+	}
+}
+
+func TestSSHAuth(t *testing.T) {
+	privateKey, knownHosts := generateSSHKey(t)
+	secret := createTypedSecret(corev1.SecretTypeSSHAuth, map[string]string{"ssh-privatekey": privateKey, "known_hosts": knownHosts})
+	g := New(fake.NewClientBuilder().WithObjects(secret).Build())
+
+	auth, err := g.SSHAuth(context.TODO(), testID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(auth.KnownHosts) != knownHosts {
+		t.Fatalf("got known hosts %q, want %q", auth.KnownHosts, knownHosts)
+	}
+	if auth.Signer.PublicKey().Type() != ssh.KeyAlgoED25519 {
+		t.Fatalf("got key type %s, want %s", auth.Signer.PublicKey().Type(), ssh.KeyAlgoED25519)
+	}
+}
+
+func TestDockerConfig(t *testing.T) {
+	configTests := []struct {
+		name       string
+		secretType corev1.SecretType
+		data       map[string]string
+	}{
+		{
+			name:       "dockerconfigjson",
+			secretType: corev1.SecretTypeDockerConfigJson,
+			data: map[string]string{".dockerconfigjson": `{"auths":{
+				"https://index.docker.io/v1/":{"auth":"dGVzdC11c2VyOnRlc3QtcGFzc3dvcmQ="},
+				"ghcr.io":{"username":"ghcr-user","password":"ghcr-password"}}}`},
+		},
+		{
+			name:       "dockercfg",
+			secretType: corev1.SecretTypeDockercfg,
+			data: map[string]string{".dockercfg": `{
+				"https://index.docker.io/v1/":{"auth":"dGVzdC11c2VyOnRlc3QtcGFzc3dvcmQ="},
+				"ghcr.io":{"username":"ghcr-user","password":"ghcr-password"}}`},
+		},
+	}
+
+	for _, tt := range configTests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New(fake.NewClientBuilder().WithObjects(createTypedSecret(tt.secretType, tt.data)).Build())
+
+			config, err := g.DockerConfig(context.TODO(), testID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := DockerConfig{
+				"index.docker.io": {Username: "test-user", Password: "test-password"},
+				"ghcr.io":         {Username: "ghcr-user", Password: "ghcr-password"},
+			}
+			if diff := cmp.Diff(want, config); diff != "" {
+				t.Fatalf("failed to get docker config:\n%s", diff)
+			}
+			if creds, ok := config.ForHost("https://index.docker.io/v1/"); !ok || creds.Username != "test-user" {
+				t.Fatalf("got %#v for index.docker.io", creds)
+			}
+		})
+	}
+}
+
+func TestCABundle(t *testing.T) {
+	caPEM := generateCACert(t)
+	g := New(fake.NewClientBuilder().WithObjects(createTypedSecret(corev1.SecretTypeOpaque, map[string]string{"ca.crt": caPEM})).Build())
+
+	bundle, err := g.CABundle(context.TODO(), testID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bundle.Certificates) != 1 || bundle.Certificates[0].Subject.CommonName != "test-ca" {
+		t.Fatalf("got certificates %#v", bundle.Certificates)
+	}
+	if string(bundle.PEM) != caPEM {
+		t.Fatalf("got PEM %q, want %q", bundle.PEM, caPEM)
+	}
+}
+
+func TestTypedSecretErrors(t *testing.T) {
+	privateKey, knownHosts := generateSSHKey(t)
+	parseTests := []struct {
+		name    string
+		parse   func(*corev1.Secret) error
+		secret  *corev1.Secret
+		wantErr string
+	}{
+		{
+			name:    "basic-auth wrong type",
+			parse:   func(s *corev1.Secret) error { _, err := ParseBasicAuth(s); return err },
+			secret:  createTypedSecret(corev1.SecretTypeOpaque, map[string]string{"username": "test-user"}),
+			wantErr: `secret test-ns/test-secret has type "Opaque", want "kubernetes.io/basic-auth"`,
+		},
+		{
+			name:    "basic-auth missing password",
+			parse:   func(s *corev1.Secret) error { _, err := ParseBasicAuth(s); return err },
+			secret:  createTypedSecret(corev1.SecretTypeBasicAuth, map[string]string{"username": "test-user"}),
+			wantErr: `secret invalid, no "password" key in test-ns/test-secret`,
+		},
+		{
+			name:    "ssh-auth invalid key",
+			parse:   func(s *corev1.Secret) error { _, err := ParseSSHAuth(s); return err },
+			secret:  createTypedSecret(corev1.SecretTypeSSHAuth, map[string]string{"ssh-privatekey": "not a key", "known_hosts": knownHosts}),
+			wantErr: `failed to parse ssh-privatekey in test-ns/test-secret: ssh: no key found`,
+		},
+		{
+			name:    "ssh-auth missing known_hosts",
+			parse:   func(s *corev1.Secret) error { _, err := ParseSSHAuth(s); return err },
+			secret:  createTypedSecret(corev1.SecretTypeSSHAuth, map[string]string{"ssh-privatekey": privateKey}),
+			wantErr: `secret invalid, no "known_hosts" key in test-ns/test-secret`,
+		},
+		{
+			name:    "ssh-auth empty known_hosts",
+			parse:   func(s *corev1.Secret) error { _, err := ParseSSHAuth(s); return err },
+			secret:  createTypedSecret(corev1.SecretTypeSSHAuth, map[string]string{"ssh-privatekey": privateKey, "known_hosts": "# no hosts\n"}),
+			wantErr: `failed to parse known_hosts in test-ns/test-secret: no host keys found`,
+		},
+		{
+			name:    "docker config wrong type",
+			parse:   func(s *corev1.Secret) error { _, err := ParseDockerConfig(s); return err },
+			secret:  createTypedSecret(corev1.SecretTypeBasicAuth, nil),
+			wantErr: `secret test-ns/test-secret has type "kubernetes.io/basic-auth", want "kubernetes.io/dockerconfigjson" or "kubernetes.io/dockercfg"`,
+		},
+		{
+			name:    "docker config invalid auth",
+			parse:   func(s *corev1.Secret) error { _, err := ParseDockerConfig(s); return err },
+			secret:  createTypedSecret(corev1.SecretTypeDockerConfigJson, map[string]string{".dockerconfigjson": `{"auths":{"ghcr.io":{"auth":"dGVzdA=="}}}`}),
+			wantErr: `failed to parse credentials for ghcr.io in test-ns/test-secret: auth is not in the form username:password`,
+		},
+		{
+			name:    "docker config invalid json",
+			parse:   func(s *corev1.Secret) error { _, err := ParseDockerConfig(s); return err },
+			secret:  createTypedSecret(corev1.SecretTypeDockerConfigJson, map[string]string{".dockerconfigjson": `{`}),
+			wantErr: `failed to parse .dockerconfigjson in test-ns/test-secret: unexpected end of JSON input`,
+		},
+		{
+			name:    "CA bundle without certificates",
+			parse:   func(s *corev1.Secret) error { _, err := ParseCABundle(s); return err },
+			secret:  createTypedSecret(corev1.SecretTypeTLS, map[string]string{"ca.crt": "not a certificate"}),
+			wantErr: `no certificates found in ca.crt in test-ns/test-secret`,
+		},
+	}
+
+	for _, tt := range parseTests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.parse(tt.secret)
+			if !test.MatchError(t, tt.wantErr, err) {
+				t.Fatalf("got %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func createTypedSecret(secretType corev1.SecretType, data map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testID.Name,
+			Namespace: testID.Namespace,
+		},
+		Type: secretType,
+		Data: map[string][]byte{},
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+	return secret
+}
+
+func generateSSHKey(t *testing.T) (string, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(block)), "github.com " + string(ssh.MarshalAuthorizedKey(sshPub))
+}
+
+func generateCACert(t *testing.T) string {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
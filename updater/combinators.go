@@ -0,0 +1,115 @@
+package updater
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// Chain is a ContentUpdater that applies each of the provided ContentUpdaters
+// in sequence, passing the output of each to the next.
+//
+// If any of the updaters fails, the error identifies the step that failed.
+func Chain(fs ...ContentUpdater) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		for i, f := range fs {
+			updated, err := f(b)
+			if err != nil {
+				return nil, fmt.Errorf("step %d of %d failed: %w", i+1, len(fs), err)
+			}
+			b = updated
+		}
+		return b, nil
+	}
+}
+
+// When is a ContentUpdater that applies f only if pred returns true for the
+// existing body, otherwise the body is returned unchanged.
+func When(pred func([]byte) bool, f ContentUpdater) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		if !pred(b) {
+			return b, nil
+		}
+		return f(b)
+	}
+}
+
+// IfExists is a ContentUpdater that applies f only if the file exists.
+//
+// Missing files are passed to ContentUpdaters as a nil body, see
+// CommitInput.AllowMissing.
+func IfExists(f ContentUpdater) ContentUpdater {
+	return When(func(b []byte) bool { return b != nil }, f)
+}
+
+// IfMissing is a ContentUpdater that applies f only if the file does not
+// exist, this can be used to provide default content for a new file.
+//
+//	Chain(IfMissing(ReplaceContents(defaults)), UpdateYAML("image", newImage))
+func IfMissing(f ContentUpdater) ContentUpdater {
+	return When(func(b []byte) bool { return b == nil }, f)
+}
+
+// Validate is a ContentUpdater that applies f and then calls the validator with
+// the updated body, if the validator returns an error, the update fails.
+func Validate(f ContentUpdater, validator func([]byte) error) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		updated, err := f(b)
+		if err != nil {
+			return nil, err
+		}
+		if err := validator(updated); err != nil {
+			return nil, fmt.Errorf("validation failed: %w", err)
+		}
+		return updated, nil
+	}
+}
+
+// Transform is a ContentUpdater that decodes the existing body before passing
+// it to f, and encodes the updated body before returning it.
+//
+//	Transform(Base64Decode, Base64Encode, UpdateYAML("test.value", "new"))
+func Transform(decode, encode, f ContentUpdater) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		decoded, err := decode(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode body: %w", err)
+		}
+		updated, err := f(decoded)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := encode(updated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode body: %w", err)
+		}
+		return encoded, nil
+	}
+}
+
+// Base64Decode decodes a standard base64 encoded body, for use with Transform.
+//
+// A nil body is returned as nil so that missing files are preserved.
+func Base64Decode(b []byte) ([]byte, error) {
+	if b == nil {
+		return nil, nil
+	}
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(b)))
+	n, err := base64.StdEncoding.Decode(decoded, b)
+	if err != nil {
+		return nil, err
+	}
+	return decoded[:n], nil
+}
+
+// Base64Encode encodes a body with standard base64 encoding, for use with
+// Transform.
+//
+// A nil body is returned as nil so that missing files are preserved.
+func Base64Encode(b []byte) ([]byte, error) {
+	if b == nil {
+		return nil, nil
+	}
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(b)))
+	base64.StdEncoding.Encode(encoded, b)
+	return encoded, nil
+}
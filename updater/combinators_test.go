@@ -0,0 +1,102 @@
+package updater
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/gitops-tools/pkg/test"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCombinators(t *testing.T) {
+	hasPrefix := func(p string) func([]byte) bool {
+		return func(b []byte) bool {
+			return bytes.HasPrefix(b, []byte(p))
+		}
+	}
+
+	combinatorTests := []struct {
+		name  string
+		input []byte
+		want  []byte
+		f     ContentUpdater
+	}{
+		{"chain with no updaters", []byte("input"), []byte("input"), Chain()},
+		{"chain applies in sequence", []byte("input:\n  value: test\n"), []byte("input:\n  other: value\n  value: new\n"),
+			Chain(UpdateYAML("input.value", "new"), UpdateYAML("input.other", "value"))},
+		{"when predicate matches", []byte("input"), []byte("output"), When(hasPrefix("in"), ReplaceContents([]byte("output")))},
+		{"when predicate does not match", []byte("input"), []byte("input"), When(hasPrefix("out"), ReplaceContents([]byte("output")))},
+		{"if exists with existing file", []byte("input"), []byte("output"), IfExists(ReplaceContents([]byte("output")))},
+		{"if exists with empty file", []byte{}, []byte("output"), IfExists(ReplaceContents([]byte("output")))},
+		{"if exists with missing file", nil, nil, IfExists(ReplaceContents([]byte("output")))},
+		{"if missing with existing file", []byte("input"), []byte("input"), IfMissing(ReplaceContents([]byte("output")))},
+		{"if missing with missing file", nil, []byte("output"), IfMissing(ReplaceContents([]byte("output")))},
+		{"if missing then update", nil, []byte("input:\n  value: new\n"),
+			Chain(IfMissing(ReplaceContents([]byte("input:\n  value: test\n"))), UpdateYAML("input.value", "new"))},
+		{"validate passes", []byte("input"), []byte("output"),
+			Validate(ReplaceContents([]byte("output")), func([]byte) error { return nil })},
+		{"transform base64", []byte("aW5wdXQ6CiAgdmFsdWU6IHRlc3QK"), []byte("aW5wdXQ6CiAgdmFsdWU6IG5ldwo="),
+			Transform(Base64Decode, Base64Encode, UpdateYAML("input.value", "new"))},
+		{"transform missing file", nil, nil, Transform(Base64Decode, Base64Encode, IfExists(ReplaceContents([]byte("output"))))},
+	}
+
+	for _, tt := range combinatorTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			got, err := tt.f(tt.input)
+
+			if err != nil {
+				rt.Errorf("got an error updating the content: %v", err)
+				return
+			}
+
+			if diff := cmp.Diff(string(tt.want), string(got)); diff != "" {
+				rt.Errorf("returned body failed:\n%s", diff)
+			}
+			if (tt.want == nil) != (got == nil) {
+				rt.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCombinators_errors(t *testing.T) {
+	testErr := errors.New("test error")
+	failing := func([]byte) ([]byte, error) {
+		return nil, testErr
+	}
+
+	errorTests := []struct {
+		name    string
+		f       ContentUpdater
+		wantErr string
+	}{
+		{"chain identifies failing step", Chain(ReplaceContents([]byte("a")), failing, ReplaceContents([]byte("b"))), "step 2 of 3 failed: test error"},
+		{"nested chains", Chain(ReplaceContents([]byte("a")), Chain(failing)), "step 2 of 2 failed: step 1 of 1 failed: test error"},
+		{"when propagates errors", When(func([]byte) bool { return true }, failing), "test error"},
+		{"validate fails", Validate(ReplaceContents([]byte("a")), func([]byte) error { return testErr }), "validation failed: test error"},
+		{"validate does not call validator on failure", Validate(failing, func([]byte) error { return errors.New("validated") }), "test error"},
+		{"transform decode failure", Transform(Base64Decode, Base64Encode, ReplaceContents([]byte("a"))), "failed to decode body: illegal base64"},
+		{"transform encode failure", Transform(Base64Encode, func([]byte) ([]byte, error) { return nil, testErr }, ReplaceContents([]byte("a"))), "failed to encode body: test error"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			_, err := tt.f([]byte("input!"))
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Errorf("got %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChain_wraps_errors(t *testing.T) {
+	testErr := errors.New("test error")
+
+	_, err := Chain(func([]byte) ([]byte, error) { return nil, testErr })([]byte("input"))
+
+	if !errors.Is(err, testErr) {
+		t.Fatalf("got %v, want wrapped %v", err, testErr)
+	}
+}
@@ -0,0 +1,111 @@
+package updater
+
+import (
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Plan records the changes that an Updater in dry-run mode would have made.
+type Plan struct {
+	Files        []FileChange
+	PullRequests []PullRequestInput
+}
+
+// FileChange describes a change to a file that would have been committed.
+type FileChange struct {
+	Repo          string // e.g. my-org/my-repo
+	Filename      string // relative path to the file in the repository
+	SourceBranch  string // e.g. main
+	Branch        string // the branch the change would be committed to
+	CreateBranch  bool   // true if the Branch would be created from the SourceBranch
+	CommitMessage string
+	Diff          string // unified diff of the change to the file
+}
+
+// DryRun is an option func for the Updater creation function.
+//
+// In dry-run mode the Updater reads files and applies the ContentUpdater, but
+// creates no branches, commits or pull requests, instead the changes are
+// recorded in the Plan.
+func DryRun() UpdaterFunc {
+	return func(u *Updater) {
+		u.dryRun = true
+	}
+}
+
+// Plan returns the changes recorded by an Updater in dry-run mode.
+func (u *Updater) Plan() Plan {
+	u.planMu.Lock()
+	defer u.planMu.Unlock()
+
+	return Plan{
+		Files:        append([]FileChange(nil), u.plan.Files...),
+		PullRequests: append([]PullRequestInput(nil), u.plan.PullRequests...),
+	}
+}
+
+func (u *Updater) planUpdate(input CommitInput, branch string, create bool, message, diff string) {
+	u.planMu.Lock()
+	defer u.planMu.Unlock()
+	u.plan.Files = append(u.plan.Files, FileChange{
+		Repo:          input.Repo,
+		Filename:      input.Filename,
+		SourceBranch:  input.Branch,
+		Branch:        branch,
+		CreateBranch:  create,
+		CommitMessage: message,
+		Diff:          diff,
+	})
+	u.log.Info("dry-run: planned update to file", "filename", input.Filename, "branch", branch)
+}
+
+func (u *Updater) planPullRequest(input PullRequestInput) *scm.PullRequest {
+	u.planMu.Lock()
+	defer u.planMu.Unlock()
+	u.plan.PullRequests = append(u.plan.PullRequests, input)
+	u.log.Info("dry-run: planned PullRequest", "title", input.Title, "branch", input.NewBranch)
+	return &scm.PullRequest{
+		Title:  input.Title,
+		Body:   input.Body,
+		Source: input.NewBranch,
+		Target: input.SourceBranch,
+	}
+}
+
+func unifiedDiff(filename string, current, updated []byte) (string, error) {
+	fromFile := "a/" + filename
+	if current == nil {
+		fromFile = "/dev/null"
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(current),
+		B:        splitLines(updated),
+		FromFile: fromFile,
+		ToFile:   "b/" + filename,
+		Context:  3,
+	})
+}
+
+// noNewlineMarker follows the last line of a file without a trailing newline
+// in a unified diff.
+const noNewlineMarker = "\\ No newline at end of file\n"
+
+// splitLines splits the body into lines, preserving the line endings, and
+// without adding an empty line when the body ends in a newline.
+//
+// If the body doesn't end in a newline, the last line is terminated and
+// followed by the noNewlineMarker, in the same way as git diff.
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if last := lines[len(lines)-1]; last == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] = last + "\n" + noNewlineMarker
+	}
+	return lines
+}
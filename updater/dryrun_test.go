@@ -0,0 +1,179 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gitops-tools/pkg/client/mock"
+	"github.com/gitops-tools/pkg/names"
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestApplyUpdateToFileWithDryRun(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n  name: test\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), DryRun())
+
+	branch, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(), UpdateYAML("test.image", "new-image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := updater.CreatePR(context.Background(), makePullRequestInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if branch != "test-branch-a" {
+		t.Fatalf("planned branch, got %#v, want %#v", branch, "test-branch-a")
+	}
+	if pr.Title != "This is a test PR" {
+		t.Fatalf("planned PullRequest, got %#v", pr)
+	}
+	m.AssertNoInteractions()
+	want := Plan{
+		Files: []FileChange{
+			{
+				Repo:          testGitHubRepo,
+				Filename:      testFilePath,
+				SourceBranch:  testBranch,
+				Branch:        "test-branch-a",
+				CreateBranch:  true,
+				CommitMessage: "just a test commit",
+				Diff: `--- a/environments/test/services/service-a/test.yaml
++++ b/environments/test/services/service-a/test.yaml
+@@ -1,3 +1,3 @@
+ test:
+-  image: old-image
++  image: new-image
+   name: test
+`,
+			},
+		},
+		PullRequests: []PullRequestInput{makePullRequestInput()},
+	}
+	if diff := cmp.Diff(want, updater.Plan()); diff != "" {
+		t.Fatalf("failed to record plan:\n%s", diff)
+	}
+}
+
+func TestApplyUpdateToFileWithDryRunReusingExistingBranch(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddBranchHead(testGitHubRepo, "test-branch-3eb0267823", testSHA)
+	updater := New(zap.New(), m, NameGenerator(names.NewHashGenerator()), DryRun())
+
+	branch, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(), UpdateYAML("test.image", "new-image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if branch != "test-branch-3eb0267823" {
+		t.Fatalf("planned branch, got %#v, want %#v", branch, "test-branch-3eb0267823")
+	}
+	m.AssertNoInteractions()
+	if plan := updater.Plan(); plan.Files[0].CreateBranch {
+		t.Fatalf("planned to create existing branch %s", plan.Files[0].Branch)
+	}
+}
+
+func TestApplyUpdateToFileWithDryRunAndMissingFile(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m, DryRun())
+
+	branch, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(func(ci *CommitInput) {
+		ci.BranchGenerateName = ""
+		ci.AllowMissing = true
+	}), ReplaceContents([]byte("test: value\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if branch != testBranch {
+		t.Fatalf("planned branch, got %#v, want %#v", branch, testBranch)
+	}
+	m.AssertNoInteractions()
+	want := []FileChange{
+		{
+			Repo:          testGitHubRepo,
+			Filename:      testFilePath,
+			SourceBranch:  testBranch,
+			Branch:        testBranch,
+			CommitMessage: "just a test commit",
+			Diff: `--- /dev/null
++++ b/environments/test/services/service-a/test.yaml
+@@ -0,0 +1 @@
++test: value
+`,
+		},
+	}
+	if diff := cmp.Diff(want, updater.Plan().Files); diff != "" {
+		t.Fatalf("failed to record plan:\n%s", diff)
+	}
+}
+
+func TestUnifiedDiffWithoutTrailingNewline(t *testing.T) {
+	diffTests := []struct {
+		name    string
+		current string
+		updated string
+		want    string
+	}{
+		{
+			name:    "updated without newline",
+			current: "test:\n  image: old-image\n",
+			updated: "test:\n  image: new-image",
+			want: `--- a/test.yaml
++++ b/test.yaml
+@@ -1,2 +1,2 @@
+ test:
+-  image: old-image
++  image: new-image
+\ No newline at end of file
+`,
+		},
+		{
+			name:    "current without newline",
+			current: "test:\n  image: old-image",
+			updated: "test:\n  image: old-image\n",
+			want: `--- a/test.yaml
++++ b/test.yaml
+@@ -1,2 +1,2 @@
+ test:
+-  image: old-image
+\ No newline at end of file
++  image: old-image
+`,
+		},
+		{
+			name:    "neither with newline",
+			current: "test:\n  image: old-image",
+			updated: "test:\n  name: test\n  image: old-image",
+			want: `--- a/test.yaml
++++ b/test.yaml
+@@ -1,2 +1,3 @@
+ test:
++  name: test
+   image: old-image
+\ No newline at end of file
+`,
+		},
+	}
+
+	for _, tt := range diffTests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := unifiedDiff("test.yaml", []byte(tt.current), []byte(tt.updated))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if d := cmp.Diff(tt.want, diff); d != "" {
+				t.Fatalf("failed to generate diff:\n%s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// DefaultPullRequestBodyTemplate is a PullRequest body template that renders a
+// table of the keys that were changed in the file.
+const DefaultPullRequestBodyTemplate = `Updated {{ .Filename }} in {{ .Repo }} ({{ .DiffSummary }}).
+{{ if .Changes }}
+| Key | Change |
+| --- | ------ |
+{{- range .Changes }}
+| {{ tableCell .Key }} | {{ tableCell .OldValue }} → {{ tableCell .NewValue }} |
+{{- end }}
+{{ end -}}
+`
+
+// TemplateContext is the data available when rendering the commit message,
+// and PullRequest title and body templates.
+//
+//	Updating {{ .Filename }} from {{ .SourceBranch }} in {{ .Branch }}
+//
+// The templates can use the tableCell function to escape a value for use in a
+// markdown table cell.
+type TemplateContext struct {
+	Repo         string         // e.g. my-org/my-repo
+	Filename     string         // relative path to the file in the repository
+	SourceBranch string         // e.g. main
+	Branch       string         // the branch the change is committed to, including generated names
+	Changes      []Change       // the keys that were changed in the file
+	Diff         string         // unified diff of the change to the file
+	DiffSummary  string         // e.g. 1 file changed, 1 insertion(+), 1 deletion(-)
+	Data         map[string]any // caller-supplied data from the TemplateData input
+}
+
+// Change describes a key that was changed in a YAML file.
+//
+// Keys are dotted paths in the same format used by UpdateYAML, and values are
+// formatted as strings, with an empty OldValue for added keys and an empty
+// NewValue for removed keys.
+//
+// In files with more than one YAML document, keys are prefixed with the index
+// of the document e.g. 1.spec.replicas.
+type Change struct {
+	Key      string
+	OldValue string
+	NewValue string
+}
+
+func newTemplateContext(input CommitInput, branch string, current, updated []byte) (*TemplateContext, error) {
+	diff, err := unifiedDiff(input.Filename, current, updated)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateContext{
+		Repo:         input.Repo,
+		Filename:     input.Filename,
+		SourceBranch: input.Branch,
+		Branch:       branch,
+		Changes:      changedKeys(current, updated),
+		Diff:         diff,
+		DiffSummary:  diffSummary(diff),
+		Data:         input.TemplateData,
+	}, nil
+}
+
+// renderTemplate renders the text template with the context, if the template
+// is empty, the fallback is returned.
+func renderTemplate(name, text, fallback string, tc *TemplateContext) (string, error) {
+	if text == "" {
+		return fallback, nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s template: %w", name, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, tc); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return b.String(), nil
+}
+
+var templateFuncs = template.FuncMap{"tableCell": tableCell}
+
+// tableCell escapes the value so that it can be rendered in a markdown table
+// cell.
+func tableCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>").Replace(s)
+}
+
+// changedKeys compares two multi-document YAML bodies and returns the keys
+// that differ.
+//
+// If either body can't be parsed as YAML, no changes are returned.
+func changedKeys(current, updated []byte) []Change {
+	before, err := yamlDocuments(current)
+	if err != nil {
+		return nil
+	}
+	after, err := yamlDocuments(updated)
+	if err != nil {
+		return nil
+	}
+	oldValues, newValues := map[string]string{}, map[string]string{}
+	multiDocument := len(before) > 1 || len(after) > 1
+	for i, doc := range before {
+		flatten(documentPrefix(i, multiDocument), doc, oldValues)
+	}
+	for i, doc := range after {
+		flatten(documentPrefix(i, multiDocument), doc, newValues)
+	}
+
+	var changes []Change
+	for k, v := range newValues {
+		if old, ok := oldValues[k]; !ok || old != v {
+			changes = append(changes, Change{Key: k, OldValue: old, NewValue: v})
+		}
+	}
+	for k, v := range oldValues {
+		if _, ok := newValues[k]; !ok {
+			changes = append(changes, Change{Key: k, OldValue: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// yamlDocuments returns the parsed documents in the YAML body, keyed by the
+// index of the document.
+func yamlDocuments(b []byte) (map[int]any, error) {
+	docs := map[int]any{}
+	err := eachDocument(b, func(i int, doc []byte) error {
+		var v any
+		if err := json.Unmarshal(doc, &v); err != nil {
+			return err
+		}
+		docs[i] = v
+		return nil
+	})
+	return docs, err
+}
+
+func documentPrefix(i int, multiDocument bool) string {
+	if !multiDocument {
+		return ""
+	}
+	return strconv.Itoa(i)
+}
+
+func flatten(prefix string, v any, values map[string]string) {
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) == 0 && prefix != "" {
+			values[prefix] = "{}"
+		}
+		for k, item := range v {
+			flatten(joinKey(prefix, k), item, values)
+		}
+	case []any:
+		if len(v) == 0 && prefix != "" {
+			values[prefix] = "[]"
+		}
+		for i, item := range v {
+			flatten(joinKey(prefix, strconv.Itoa(i)), item, values)
+		}
+	case nil:
+		if prefix != "" {
+			values[prefix] = "null"
+		}
+	case string:
+		values[prefix] = v
+	default:
+		b, _ := json.Marshal(v)
+		values[prefix] = string(b)
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func diffSummary(diff string) string {
+	var insertions, deletions int
+	inHunk := false
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case !inHunk:
+		case strings.HasPrefix(line, "+"):
+			insertions++
+		case strings.HasPrefix(line, "-"):
+			deletions++
+		}
+	}
+	files := 0
+	if diff != "" {
+		files = 1
+	}
+	return fmt.Sprintf("%d file%s changed, %d insertion%s(+), %d deletion%s(-)",
+		files, plural(files), insertions, plural(insertions), deletions, plural(deletions))
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
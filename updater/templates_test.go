@@ -0,0 +1,158 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gitops-tools/pkg/client/mock"
+	"github.com/gitops-tools/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestChangedKeys(t *testing.T) {
+	changeTests := []struct {
+		name    string
+		current string
+		updated string
+		want    []Change
+	}{
+		{"no changes", "test:\n  value: test\n", "test:\n  value: test\n", nil},
+		{"changed key", "test:\n  value: test\n", "test:\n  value: new\n", []Change{{Key: "test.value", OldValue: "test", NewValue: "new"}}},
+		{"added and removed keys", "test:\n  old: 1\n", "test:\n  new: true\n",
+			[]Change{{Key: "test.new", NewValue: "true"}, {Key: "test.old", OldValue: "1"}}},
+		{"list items", "items:\n- age: 30\n- age: 29\n", "items:\n- age: 30\n- age: 20\n", []Change{{Key: "items.1.age", OldValue: "29", NewValue: "20"}}},
+		{"missing file", "", "test: value\n", []Change{{Key: "test", NewValue: "value"}}},
+		{"not yaml", "test: value\n", ": value\n", nil},
+		{"multiple documents", "test: value\n---\ntest: other\n", "test: value\n---\ntest: new\n", []Change{{Key: "1.test", OldValue: "other", NewValue: "new"}}},
+		{"added document", "test: value\n", "test: value\n---\ntest: new\n", []Change{{Key: "1.test", NewValue: "new"}}},
+		{"invalid later document", "test: value\n", "test: value\n---\n: new\n", nil},
+	}
+
+	for _, tt := range changeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := changedKeys([]byte(tt.current), []byte(tt.updated))
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("changedKeys() failed:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tc := &TemplateContext{
+		Repo:         testGitHubRepo,
+		Filename:     testFilePath,
+		SourceBranch: testBranch,
+		Branch:       "test-branch-a",
+		Changes:      []Change{{Key: "test.image", OldValue: "old-image", NewValue: "new-image"}},
+		DiffSummary:  "1 file changed, 1 insertion(+), 1 deletion(-)",
+		Data:         map[string]any{"Author": "test-user", "Cell": "a | b\nc"},
+	}
+	templateTests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr string
+	}{
+		{"no template", "", "fallback", ""},
+		{"simple template", "Update {{ .Filename }} on {{ .Branch }} for {{ .Data.Author }}", "Update environments/test/services/service-a/test.yaml on test-branch-a for test-user", ""},
+		{"default body", DefaultPullRequestBodyTemplate, `Updated environments/test/services/service-a/test.yaml in testorg/testrepo (1 file changed, 1 insertion(+), 1 deletion(-)).
+
+| Key | Change |
+| --- | ------ |
+| test.image | old-image → new-image |
+`, ""},
+		{"table cell", "{{ tableCell .Data.Cell }}", `a \| b<br>c`, ""},
+		{"missing data", "{{ .Data.Unknown }}", "", `failed to render test template: .*map has no entry for key "Unknown"`},
+		{"invalid template", "{{ .Data.Unknown", "", `failed to parse test template: .*unclosed action`},
+	}
+
+	for _, tt := range templateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderTemplate("test", tt.text, "fallback", tc)
+
+			if !test.MatchError(t, tt.wantErr, err) {
+				t.Fatalf("got %v, want %s", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("rendered template failed:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyUpdateToFileWithCommitMessageTemplate(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), DryRun())
+
+	_, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(func(ci *CommitInput) {
+		ci.CommitMessageTemplate = "Update {{ range .Changes }}{{ .Key }} to {{ .NewValue }}{{ end }} ({{ .Data.Reason }})"
+		ci.TemplateData = map[string]any{"Reason": "testing"}
+	}), UpdateYAML("test.image", "new-image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Update test.image to new-image (testing)"
+	if msg := updater.Plan().Files[0].CommitMessage; msg != want {
+		t.Fatalf("got commit message %q, want %q", msg, want)
+	}
+}
+
+func TestApplyUpdateAndCreatePR(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	pr, err := updater.ApplyUpdateAndCreatePR(context.Background(), makeCommitInput(),
+		PullRequestInput{
+			TitleTemplate: "Update {{ .Filename }} for {{ .Data.Team }}",
+			BodyTemplate:  DefaultPullRequestBodyTemplate,
+			TemplateData:  map[string]any{"Team": "test-team"},
+		},
+		UpdateYAML("test.image", "new-image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pr.Link != "https://example.com/pull-request/1" {
+		t.Fatalf("link to PR is incorrect: got %#v, want %#v", pr.Link, "https://example.com/pull-request/1")
+	}
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-a", testSHA)
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "Update environments/test/services/service-a/test.yaml for test-team",
+		Body: `Updated environments/test/services/service-a/test.yaml in testorg/testrepo (1 file changed, 1 insertion(+), 1 deletion(-)).
+
+| Key | Change |
+| --- | ------ |
+| test.image | old-image → new-image |
+`,
+		Head: "test-branch-a",
+		Base: testBranch,
+	})
+}
+
+func TestCreatePullRequestWithTemplates(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m)
+	input := makePullRequestInput()
+	input.TitleTemplate = "Merge {{ .Branch }} into {{ .SourceBranch }}"
+
+	_, err := updater.CreatePR(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "Merge test-branch-a into main",
+		Body:  input.Body,
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
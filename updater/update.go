@@ -1,8 +1,12 @@
 package updater
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"github.com/jenkins-x/go-scm/scm"
@@ -26,6 +30,14 @@ type CommitInput struct {
 	NewBranchName      string // e.g. feature-update-image
 	BranchGenerateName string // e.g. update-image-
 	CommitMessage      string // This is used for the commit when updating the file
+	AllowMissing       bool   // If true, a missing file is passed to the ContentUpdater as a nil body
+	BranchKey          string // Optional key used by a names.KeyedGenerator in place of the updated content
+
+	// CommitMessageTemplate is a text/template that is rendered with a
+	// TemplateContext, if provided, it is used in place of the CommitMessage.
+	CommitMessageTemplate string
+	// TemplateData is made available to templates as .Data
+	TemplateData map[string]any
 }
 
 // PullRequestInput provides configuration for the PullRequest to be opened.
@@ -35,15 +47,61 @@ type PullRequestInput struct {
 	Repo         string // e.g. my-org/my-repo
 	Title        string
 	Body         string
+
+	// TitleTemplate and BodyTemplate are text/templates that are rendered with
+	// a TemplateContext, if provided they are used in place of the Title and
+	// Body.
+	TitleTemplate string
+	BodyTemplate  string
+	// TemplateData is made available to templates as .Data
+	TemplateData map[string]any
 }
 
 // NameGenerator is an option func for the Updater creation function.
+//
+// If the generator is a names.KeyedGenerator, branch names are derived from the
+// change, and if the branch already exists it is reused rather than created.
 func NameGenerator(g names.Generator) UpdaterFunc {
 	return func(u *Updater) {
 		u.nameGenerator = g
 	}
 }
 
+// Validators is an option func for the Updater creation function.
+//
+// The Validators are called with the updated content of each file before any
+// branch is created, and the update fails if any of them return an error.
+func Validators(v ...Validator) UpdaterFunc {
+	return func(u *Updater) {
+		u.validators = append(u.validators, v...)
+	}
+}
+
+// UniqueBranchNames is an option func for the Updater creation function.
+//
+// Generated branch names are checked against the existing branches in the
+// repository, and a new name is generated up to attempts times, if no unused
+// name is found, a NoAvailableBranchNameError is returned.
+//
+// This does not apply to names.KeyedGenerators, which reuse existing branches.
+func UniqueBranchNames(attempts int) UpdaterFunc {
+	return func(u *Updater) {
+		u.branchNameAttempts = attempts
+	}
+}
+
+// NoAvailableBranchNameError is returned when the Updater is unable to
+// generate a branch name that doesn't already exist.
+type NoAvailableBranchNameError struct {
+	Repo     string
+	Prefix   string
+	Attempts int
+}
+
+func (e NoAvailableBranchNameError) Error() string {
+	return fmt.Sprintf("failed to generate an unused branch name with prefix %q in repo %s after %d attempts", e.Prefix, e.Repo, e.Attempts)
+}
+
 // New creates and returns a new Updater.
 func New(l logr.Logger, c client.GitClient, opts ...UpdaterFunc) *Updater {
 	u := &Updater{gitClient: c, nameGenerator: names.New(), log: l}
@@ -55,69 +113,288 @@ func New(l logr.Logger, c client.GitClient, opts ...UpdaterFunc) *Updater {
 
 // Updater can update a Git repo with an updated version of a file.
 type Updater struct {
-	gitClient     client.GitClient
-	nameGenerator names.Generator
-	log           logr.Logger
+	gitClient          client.GitClient
+	nameGenerator      names.Generator
+	branchNameAttempts int
+	validators         []Validator
+	log                logr.Logger
+
+	dryRun bool
+	planMu sync.Mutex
+	plan   Plan
 }
 
 // ApplyUpdateToFile does the job of fetching the existing file, passing it to a
 // user-provided function, and optionally creating a PR.
+//
+// If input.AllowMissing is true and the file does not exist, the function is
+// called with a nil body, and the file is created with the returned content.
 func (u *Updater) ApplyUpdateToFile(ctx context.Context, input CommitInput, f ContentUpdater) (string, error) {
-	current, err := u.gitClient.GetFile(ctx, input.Repo, input.Branch, input.Filename)
+	tc, _, err := u.applyUpdateToFile(ctx, input, f)
 	if err != nil {
-		u.log.Info("failed to get file from repo", "err", err)
 		return "", err
 	}
-	u.log.Info("got existing file", "sha", current.Sha)
+	return tc.Branch, nil
+}
+
+// ApplyUpdateAndCreatePR applies the update to the file in the same way as
+// ApplyUpdateToFile, and then opens a PullRequest from the branch with the
+// change.
+//
+// The PullRequest templates are rendered with the details of the change to
+// the file.
+//
+// If the PullRequestInput doesn't provide a Repo or SourceBranch, they are
+// taken from the CommitInput.
+//
+// If an existing branch was reused and it already has an open PullRequest,
+// the title and body of that PullRequest are updated instead, this requires a
+// GitClient that implements client.PullRequestUpdater.
+//
+// Only open PullRequests are updated, if the PullRequest for a reused branch
+// was merged or closed, a new PullRequest is opened from the branch, and if
+// the branch has no changes that aren't in the SourceBranch, most services
+// will fail to create it. Use a BranchKey that identifies each change to avoid
+// reusing the branches of merged PullRequests.
+func (u *Updater) ApplyUpdateAndCreatePR(ctx context.Context, input CommitInput, pr PullRequestInput, f ContentUpdater) (*scm.PullRequest, error) {
+	tc, reused, err := u.applyUpdateToFile(ctx, input, f)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Repo == "" {
+		pr.Repo = input.Repo
+	}
+	if pr.SourceBranch == "" {
+		pr.SourceBranch = input.Branch
+	}
+	pr.NewBranch = tc.Branch
+	prContext := *tc
+	prContext.Data = pr.TemplateData
+	return u.createPR(ctx, pr, &prContext, reused)
+}
+
+// applyUpdateToFile returns the TemplateContext for the change, and true if
+// an existing branch with a deterministic name was reused.
+func (u *Updater) applyUpdateToFile(ctx context.Context, input CommitInput, f ContentUpdater) (*TemplateContext, bool, error) {
+	current, err := u.gitClient.GetFile(ctx, input.Repo, input.Branch, input.Filename)
+	switch {
+	case err == nil:
+		u.log.Info("got existing file", "sha", current.Sha)
+	case input.AllowMissing && client.IsNotFound(err):
+		u.log.Info("file does not exist in repo", "filename", input.Filename)
+		current = &scm.Content{}
+	default:
+		u.log.Info("failed to get file from repo", "err", err)
+		return nil, false, err
+	}
 	updated, err := f(current.Data)
 	if err != nil {
-		return "", err
+		return nil, false, err
+	}
+	if current.Data == nil && updated == nil {
+		return nil, false, fmt.Errorf("file %s does not exist and no content was provided", input.Filename)
 	}
-	return u.applyUpdate(ctx, input, current.Sha, updated)
+	for _, v := range u.validators {
+		if err := v.Validate(updated); err != nil {
+			return nil, false, fmt.Errorf("failed to validate %s: %w", input.Filename, err)
+		}
+	}
+	branch, create, err := u.branchName(ctx, input, updated)
+	if err != nil {
+		return nil, false, err
+	}
+	reused, err := u.reuseBranch(ctx, input, branch, create)
+	if err != nil {
+		return nil, false, err
+	}
+	tc, err := newTemplateContext(input, branch, current.Data, updated)
+	if err != nil {
+		return nil, false, err
+	}
+	message, err := renderTemplate("commit message", input.CommitMessageTemplate, input.CommitMessage, tc)
+	if err != nil {
+		return nil, false, err
+	}
+	if u.dryRun {
+		u.planUpdate(input, branch, create && !reused, message, tc.Diff)
+		return tc, reused, nil
+	}
+	if err := u.applyUpdate(ctx, input, branch, create && !reused, reused, message, current.Sha, updated); err != nil {
+		return nil, false, err
+	}
+	return tc, reused, nil
 }
 
-func (u *Updater) applyUpdate(ctx context.Context, input CommitInput, currentSHA string, newBody []byte) (string, error) {
+func (u *Updater) applyUpdate(ctx context.Context, input CommitInput, branch string, create, reused bool, message, currentSHA string, newBody []byte) error {
 	branchRef, err := u.gitClient.GetBranchHead(ctx, input.Repo, input.Branch)
 	if err != nil {
-		return "", fmt.Errorf("failed to get branch head: %v", err)
+		return fmt.Errorf("failed to get branch head: %v", err)
 	}
-	newBranchName, err := u.createBranchIfNecessary(ctx, input, branchRef)
-	if err != nil {
-		return "", err
+	if err := u.createBranchIfNecessary(ctx, input, branch, create, reused, branchRef); err != nil {
+		return err
+	}
+	if reused {
+		existing, err := u.gitClient.GetFile(ctx, input.Repo, branch, input.Filename)
+		switch {
+		case err == nil && bytes.Equal(existing.Data, newBody):
+			u.log.Info("file is already up to date in branch", "filename", input.Filename, "branch", branch)
+			return nil
+		case err == nil:
+			currentSHA = existing.Sha
+		case client.IsNotFound(err):
+			currentSHA = ""
+		default:
+			return fmt.Errorf("failed to get file from existing branch: %w", err)
+		}
+	}
+	if creator, ok := u.gitClient.(client.FileCreator); ok && currentSHA == "" {
+		if err := creator.CreateFile(ctx, input.Repo, branch, input.Filename, message, newBody); err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+		u.log.Info("created file", "filename", input.Filename)
+		return nil
 	}
-	err = u.gitClient.UpdateFile(ctx, input.Repo, newBranchName, input.Filename, input.CommitMessage, currentSHA, newBody)
+	err = u.gitClient.UpdateFile(ctx, input.Repo, branch, input.Filename, message, currentSHA, newBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to update file: %w", err)
+		return fmt.Errorf("failed to update file: %w", err)
 	}
 	u.log.Info("updated file", "filename", input.Filename)
-	return newBranchName, nil
+	return nil
 }
 
-func (u *Updater) createBranchIfNecessary(ctx context.Context, input CommitInput, sourceRef string) (string, error) {
-	newBranchName := input.NewBranchName
-	if input.BranchGenerateName == "" && newBranchName == "" {
+// reuseBranch returns true if the branch would be created, but it has a
+// deterministic name and already exists, so it is reused.
+func (u *Updater) reuseBranch(ctx context.Context, input CommitInput, branch string, create bool) (bool, error) {
+	if !create || !u.isKeyedBranch(input) {
+		return false, nil
+	}
+	_, err := u.gitClient.GetBranchHead(ctx, input.Repo, branch)
+	if err == nil {
+		return true, nil
+	}
+	if !client.IsNotFound(err) {
+		return false, fmt.Errorf("failed to check for existing branch: %w", err)
+	}
+	return false, nil
+}
+
+// createBranchIfNecessary creates the branch from the sourceRef if needed.
+func (u *Updater) createBranchIfNecessary(ctx context.Context, input CommitInput, branch string, create, reused bool, sourceRef string) error {
+	if reused {
+		u.log.Info("reusing existing branch", "branch", branch)
+		return nil
+	}
+	if !create {
 		u.log.Info("no branchGenerateName/newBranchName configured, reusing source branch", "branch", input.Branch)
-		return input.Branch, nil
+		return nil
+	}
+	err := u.gitClient.CreateBranch(ctx, input.Repo, branch, sourceRef)
+	if err != nil {
+		return fmt.Errorf("failed to create branch: %w", err)
 	}
-	if newBranchName == "" {
-		newBranchName = u.nameGenerator.PrefixedName(input.BranchGenerateName)
+	u.log.Info("created branch", "branch", branch, "ref", sourceRef)
+	return nil
+}
+
+// branchName returns the name of the branch to commit the change to, and
+// whether or not that branch needs to be created.
+//
+// If the name generator is a names.KeyedGenerator, the name is derived from the
+// BranchKey, or the repo, filename and updated content.
+func (u *Updater) branchName(ctx context.Context, input CommitInput, updated []byte) (string, bool, error) {
+	if input.BranchGenerateName == "" && input.NewBranchName == "" {
+		return input.Branch, false, nil
+	}
+	if input.NewBranchName != "" {
+		return input.NewBranchName, true, nil
+	}
+	if g, ok := u.nameGenerator.(names.KeyedGenerator); ok {
+		keys := []string{input.BranchKey}
+		if input.BranchKey == "" {
+			sum := sha256.Sum256(updated)
+			keys = []string{input.Repo, input.Filename, hex.EncodeToString(sum[:])}
+		}
+		newBranchName := g.KeyedName(input.BranchGenerateName, keys...)
 		u.log.Info("generating new branch", "name", newBranchName)
+		return newBranchName, true, nil
 	}
-	err := u.gitClient.CreateBranch(ctx, input.Repo, newBranchName, sourceRef)
-	if err != nil {
-		return "", fmt.Errorf("failed to create branch: %w", err)
+	if u.branchNameAttempts <= 0 {
+		newBranchName := u.nameGenerator.PrefixedName(input.BranchGenerateName)
+		u.log.Info("generating new branch", "name", newBranchName)
+		return newBranchName, true, nil
 	}
-	u.log.Info("created branch", "branch", newBranchName, "ref", sourceRef)
-	return newBranchName, nil
+	for i := 0; i < u.branchNameAttempts; i++ {
+		newBranchName := u.nameGenerator.PrefixedName(input.BranchGenerateName)
+		_, err := u.gitClient.GetBranchHead(ctx, input.Repo, newBranchName)
+		if client.IsNotFound(err) {
+			u.log.Info("generating new branch", "name", newBranchName)
+			return newBranchName, true, nil
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("failed to check for existing branch: %w", err)
+		}
+		u.log.Info("generated branch name already exists", "name", newBranchName)
+	}
+	return "", false, NoAvailableBranchNameError{Repo: input.Repo, Prefix: input.BranchGenerateName, Attempts: u.branchNameAttempts}
+}
+
+// isKeyedBranch returns true if the branch name for the input is generated by
+// a names.KeyedGenerator.
+func (u *Updater) isKeyedBranch(input CommitInput) bool {
+	_, ok := u.nameGenerator.(names.KeyedGenerator)
+	return ok && input.NewBranchName == "" && input.BranchGenerateName != ""
 }
 
+// CreatePR opens a PullRequest from the NewBranch to the SourceBranch.
+//
+// The PullRequest templates are rendered with a TemplateContext that has no
+// file changes, use ApplyUpdateAndCreatePR to include them.
 func (u *Updater) CreatePR(ctx context.Context, input PullRequestInput) (*scm.PullRequest, error) {
-	pr, err := u.gitClient.CreatePullRequest(ctx, input.Repo, &scm.PullRequestInput{
-		Title: input.Title,
-		Body:  input.Body,
+	return u.createPR(ctx, input, &TemplateContext{
+		Repo:         input.Repo,
+		SourceBranch: input.SourceBranch,
+		Branch:       input.NewBranch,
+		Data:         input.TemplateData,
+	}, false)
+}
+
+// createPR opens a PullRequest from the NewBranch, or if the branch was reused
+// and has an open PullRequest, updates it.
+func (u *Updater) createPR(ctx context.Context, input PullRequestInput, tc *TemplateContext, reused bool) (*scm.PullRequest, error) {
+	title, err := renderTemplate("title", input.TitleTemplate, input.Title, tc)
+	if err != nil {
+		return nil, err
+	}
+	body, err := renderTemplate("body", input.BodyTemplate, input.Body, tc)
+	if err != nil {
+		return nil, err
+	}
+	if u.dryRun {
+		input.Title, input.Body = title, body
+		return u.planPullRequest(input), nil
+	}
+	prInput := &scm.PullRequestInput{
+		Title: title,
+		Body:  body,
 		Head:  input.NewBranch,
 		Base:  input.SourceBranch,
-	})
+	}
+	if updater, ok := u.gitClient.(client.PullRequestUpdater); ok && reused {
+		existing, err := updater.FindPullRequest(ctx, input.Repo, input.NewBranch, input.SourceBranch)
+		switch {
+		case err == nil:
+			pr, err := updater.UpdatePullRequest(ctx, input.Repo, existing.Number, prInput)
+			if err != nil {
+				return nil, fmt.Errorf("failed to update pull request %d: %w", existing.Number, err)
+			}
+			u.log.Info("updated existing PullRequest", "number", pr.Number)
+			return pr, nil
+		case !client.IsNotFound(err):
+			return nil, fmt.Errorf("failed to find an existing pull request: %w", err)
+		}
+		u.log.Info("no open PullRequest for reused branch", "branch", input.NewBranch)
+	}
+	pr, err := u.gitClient.CreatePullRequest(ctx, input.Repo, prInput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a pull request: %w", err)
 	}
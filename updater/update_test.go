@@ -5,7 +5,9 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/gitops-tools/pkg/client"
 	"github.com/gitops-tools/pkg/client/mock"
+	"github.com/gitops-tools/pkg/names"
 	"github.com/jenkins-x/go-scm/scm"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
@@ -74,6 +76,208 @@ func TestApplyUpdateToFileWithNewBranchName(t *testing.T) {
 	m.AssertNoPullRequestsCreated()
 }
 
+func TestApplyUpdateToFileWithKeyedGenerator(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(names.NewHashGenerator()))
+
+	branch, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(), UpdateYAML("test.image", "new-image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyed, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(func(ci *CommitInput) {
+		ci.BranchKey = "update-image"
+	}), UpdateYAML("test.image", "new-image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if branch != "test-branch-3eb0267823" {
+		t.Fatalf("newly created branch, got %#v, want %#v", branch, "test-branch-3eb0267823")
+	}
+	if keyed == branch {
+		t.Fatalf("branch key was not used to generate the name, got %#v", keyed)
+	}
+	m.AssertBranchCreated(testGitHubRepo, branch, testSHA)
+	m.AssertBranchCreated(testGitHubRepo, keyed, testSHA)
+}
+
+func TestApplyUpdateToFileReusingExistingBranch(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddBranchHead(testGitHubRepo, "test-branch-3eb0267823", testSHA)
+	m.AddFileContents(testGitHubRepo, testFilePath, "test-branch-3eb0267823", []byte("test:\n  image: other-image\n"))
+	updater := New(zap.New(), m, NameGenerator(names.NewHashGenerator()))
+
+	branch, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(), UpdateYAML("test.image", "new-image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if branch != "test-branch-3eb0267823" {
+		t.Fatalf("reused branch, got %#v, want %#v", branch, "test-branch-3eb0267823")
+	}
+	m.AssertNoBranchesCreated()
+	updated := m.GetUpdatedContents(testGitHubRepo, testFilePath, branch)
+	if s := string(updated); s != "test:\n  image: new-image\n" {
+		t.Fatalf("update failed, got %#v", s)
+	}
+}
+
+func TestApplyUpdateAndCreatePRReusingExistingBranch(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddBranchHead(testGitHubRepo, "test-branch-3eb0267823", testSHA)
+	m.AddPullRequest(testGitHubRepo, &scm.PullRequest{Number: 3, Source: "test-branch-3eb0267823", Target: testBranch})
+	updater := New(zap.New(), m, NameGenerator(names.NewHashGenerator()))
+	input := makePullRequestInput()
+
+	pr, err := updater.ApplyUpdateAndCreatePR(context.Background(), makeCommitInput(), input, UpdateYAML("test.image", "new-image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pr.Number != 3 {
+		t.Fatalf("got PullRequest %d, want 3", pr.Number)
+	}
+	m.AssertNoPullRequestsCreated()
+	m.AssertPullRequestUpdated(testGitHubRepo, 3, &scm.PullRequestInput{
+		Title: input.Title,
+		Body:  input.Body,
+		Head:  "test-branch-3eb0267823",
+		Base:  testBranch,
+	})
+}
+
+func TestApplyUpdateAndCreatePRReusingExistingBranchWithoutPullRequest(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddBranchHead(testGitHubRepo, "test-branch-3eb0267823", testSHA)
+	m.AddPullRequest(testGitHubRepo, &scm.PullRequest{Number: 3, Source: "test-branch-3eb0267823", Target: testBranch, Closed: true})
+	updater := New(zap.New(), m, NameGenerator(names.NewHashGenerator()))
+	input := makePullRequestInput()
+
+	_, err := updater.ApplyUpdateAndCreatePR(context.Background(), makeCommitInput(), input, UpdateYAML("test.image", "new-image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.Title,
+		Body:  input.Body,
+		Head:  "test-branch-3eb0267823",
+		Base:  testBranch,
+	})
+}
+
+func TestApplyUpdateAndCreatePRReusingExistingBranchWithoutPullRequestUpdater(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddBranchHead(testGitHubRepo, "test-branch-3eb0267823", testSHA)
+	m.AddPullRequest(testGitHubRepo, &scm.PullRequest{Number: 3, Source: "test-branch-3eb0267823", Target: testBranch})
+	updater := New(zap.New(), gitClient{m}, NameGenerator(names.NewHashGenerator()))
+	input := makePullRequestInput()
+
+	_, err := updater.ApplyUpdateAndCreatePR(context.Background(), makeCommitInput(), input, UpdateYAML("test.image", "new-image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.Title,
+		Body:  input.Body,
+		Head:  "test-branch-3eb0267823",
+		Base:  testBranch,
+	})
+}
+
+func TestApplyUpdateToFileReusingUpToDateBranch(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddBranchHead(testGitHubRepo, "test-branch-3eb0267823", testSHA)
+	m.AddFileContents(testGitHubRepo, testFilePath, "test-branch-3eb0267823", []byte("test:\n  image: new-image\n"))
+	updater := New(zap.New(), m, NameGenerator(names.NewHashGenerator()))
+
+	branch, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(), UpdateYAML("test.image", "new-image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if branch != "test-branch-3eb0267823" {
+		t.Fatalf("reused branch, got %#v, want %#v", branch, "test-branch-3eb0267823")
+	}
+	m.AssertNoInteractions()
+}
+
+func TestApplyUpdateToFileWithUniqueBranchNames(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddBranchHead(testGitHubRepo, "test-branch-a", testSHA)
+	m.AddBranchHead(testGitHubRepo, "test-branch-b", testSHA)
+	updater := New(zap.New(), m, NameGenerator(&sequenceNameGenerator{names: []string{"a", "b", "c"}}), UniqueBranchNames(3))
+
+	branch, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(), ReplaceContents([]byte("new content")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if branch != "test-branch-c" {
+		t.Fatalf("newly created branch, got %#v, want %#v", branch, "test-branch-c")
+	}
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-c", testSHA)
+}
+
+func TestApplyUpdateToFileWithNoUniqueBranchNames(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddBranchHead(testGitHubRepo, "test-branch-a", testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), UniqueBranchNames(2))
+
+	_, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(), ReplaceContents([]byte("new content")))
+
+	var nameErr NoAvailableBranchNameError
+	if !errors.As(err, &nameErr) {
+		t.Fatalf("got %v, want NoAvailableBranchNameError", err)
+	}
+	want := NoAvailableBranchNameError{Repo: testGitHubRepo, Prefix: "test-branch-", Attempts: 2}
+	if nameErr != want {
+		t.Fatalf("got %#v, want %#v", nameErr, want)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestApplyUpdateToFileWithExistingBranch(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddBranchHead(testGitHubRepo, "test-branch-a", testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	_, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(), ReplaceContents([]byte("new content")))
+
+	if err.Error() != "failed to create branch: branch test-branch-a already exists in repo testorg/testrepo" {
+		t.Fatalf("got %s", err)
+	}
+	m.AssertNoInteractions()
+}
+
 func TestApplyUpdateToFileMissingFile(t *testing.T) {
 	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
 	m := mock.New(t)
@@ -98,6 +302,49 @@ func TestApplyUpdateToFileMissingFile(t *testing.T) {
 	m.AssertNoPullRequestsCreated()
 }
 
+func TestApplyUpdateToFileAllowingMissingFile(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	branch, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(
+		func(ci *CommitInput) {
+			ci.AllowMissing = true
+		}),
+		IfMissing(ReplaceContents([]byte("new content"))))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := m.GetCreatedContents(testGitHubRepo, testFilePath, branch)
+	if s := string(created); s != "new content" {
+		t.Fatalf("create failed, got %#v, want %#v", s, "new content")
+	}
+	if updated := m.GetUpdatedContents(testGitHubRepo, testFilePath, branch); updated != nil {
+		t.Fatalf("missing file was updated, got %#v", string(updated))
+	}
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-a", testSHA)
+}
+
+func TestApplyUpdateToFileAllowingMissingFileWithNoContent(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	_, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(
+		func(ci *CommitInput) {
+			ci.AllowMissing = true
+		}),
+		IfExists(ReplaceContents([]byte("new content"))))
+
+	if err.Error() != "file environments/test/services/service-a/test.yaml does not exist and no content was provided" {
+		t.Fatalf("got %s", err)
+	}
+	m.AssertNoInteractions()
+}
+
 func TestApplyUpdateToFileWithBranchCreationFailure(t *testing.T) {
 	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
 	m := mock.New(t)
@@ -122,6 +369,40 @@ func TestApplyUpdateToFileWithBranchCreationFailure(t *testing.T) {
 	m.AssertNoPullRequestsCreated()
 }
 
+func TestApplyUpdateToFileWithFailingValidator(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}),
+		Validators(YAMLValidator(), KubernetesObjectValidator()))
+
+	_, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(), UpdateYAML("test.image", "new-image"))
+
+	want := "failed to validate environments/test/services/service-a/test.yaml: document 0: missing apiVersion\nmissing kind\nmissing metadata.name"
+	if err == nil || err.Error() != want {
+		t.Fatalf("got %v, want %s", err, want)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestApplyUpdateToFileWithPassingValidator(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte(testDeployment))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}),
+		Validators(YAMLValidator(), SchemeValidator(nil)))
+
+	branch, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(),
+		UpdateYAML("spec.template.spec.containers.0.image", "new-image"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertBranchCreated(testGitHubRepo, branch, testSHA)
+}
+
 func TestCreatePullRequest(t *testing.T) {
 	m := mock.New(t)
 	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
@@ -157,6 +438,11 @@ func TestCreatePullRequestHandlingErrors(t *testing.T) {
 	}
 }
 
+// gitClient hides the optional interfaces implemented by the wrapped client.
+type gitClient struct {
+	client.GitClient
+}
+
 type stubNameGenerator struct {
 	name string
 }
@@ -165,6 +451,17 @@ func (s stubNameGenerator) PrefixedName(p string) string {
 	return p + s.name
 }
 
+type sequenceNameGenerator struct {
+	names []string
+	next  int
+}
+
+func (s *sequenceNameGenerator) PrefixedName(p string) string {
+	name := p + s.names[s.next]
+	s.next = (s.next + 1) % len(s.names)
+	return name
+}
+
 func makeCommitInput(opts ...func(*CommitInput)) CommitInput {
 	ci := CommitInput{
 		Repo:               testGitHubRepo,
@@ -0,0 +1,114 @@
+package updater
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// Validator is implemented by values that can check the updated content of a
+// file before it is committed.
+type Validator interface {
+	Validate(b []byte) error
+}
+
+// ValidatorFunc is an adapter to allow the use of ordinary functions as
+// Validators.
+type ValidatorFunc func(b []byte) error
+
+// Validate implements the Validator interface.
+func (f ValidatorFunc) Validate(b []byte) error {
+	return f(b)
+}
+
+// YAMLValidator returns a Validator that fails if any of the documents in the
+// body can't be parsed as YAML.
+func YAMLValidator() Validator {
+	return ValidatorFunc(func(b []byte) error {
+		return eachDocument(b, func(int, []byte) error {
+			return nil
+		})
+	})
+}
+
+// KubernetesObjectValidator returns a Validator that fails if any of the
+// documents in the body is not a Kubernetes object with an apiVersion, kind
+// and metadata.name.
+func KubernetesObjectValidator() Validator {
+	return ValidatorFunc(func(b []byte) error {
+		return eachDocument(b, func(i int, doc []byte) error {
+			obj := map[string]any{}
+			if err := yaml.Unmarshal(doc, &obj); err != nil {
+				return fmt.Errorf("document %d: %w", i, err)
+			}
+			u := unstructured.Unstructured{Object: obj}
+			var missing []error
+			if u.GetAPIVersion() == "" {
+				missing = append(missing, errors.New("missing apiVersion"))
+			}
+			if u.GetKind() == "" {
+				missing = append(missing, errors.New("missing kind"))
+			}
+			if u.GetName() == "" {
+				missing = append(missing, errors.New("missing metadata.name"))
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf("document %d: %w", i, errors.Join(missing...))
+			}
+			return nil
+		})
+	})
+}
+
+// SchemeValidator returns a Validator that fails if any of the documents in the
+// body can't be decoded into a typed object registered in the scheme.
+//
+// If no scheme is provided, the client-go scheme is used.
+func SchemeValidator(s *runtime.Scheme) Validator {
+	if s == nil {
+		s = clientgoscheme.Scheme
+	}
+	decoder := serializer.NewCodecFactory(s, serializer.EnableStrict).UniversalDeserializer()
+	return ValidatorFunc(func(b []byte) error {
+		return eachDocument(b, func(i int, doc []byte) error {
+			if _, _, err := decoder.Decode(doc, nil, nil); err != nil {
+				return fmt.Errorf("document %d: %w", i, err)
+			}
+			return nil
+		})
+	})
+}
+
+// eachDocument splits a YAML body into documents, and calls f with each
+// non-empty document converted to JSON.
+func eachDocument(b []byte, f func(int, []byte) error) error {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(b)))
+	for i := 0; ; i++ {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("document %d: %w", i, err)
+		}
+		j, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			return fmt.Errorf("document %d: %w", i, err)
+		}
+		if bytes.Equal(j, []byte("null")) {
+			continue
+		}
+		if err := f(i, j); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package updater
+
+import (
+	"testing"
+
+	"github.com/gitops-tools/pkg/test"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const testDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test-deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: test
+        image: test-image
+`
+
+const testConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test-config
+data:
+  key: value
+`
+
+func TestValidators(t *testing.T) {
+	validatorTests := []struct {
+		name    string
+		v       Validator
+		body    string
+		wantErr string
+	}{
+		{"valid yaml", YAMLValidator(), "test:\n  value: test\n", ""},
+		{"empty yaml", YAMLValidator(), "", ""},
+		{"multiple documents", YAMLValidator(), "test: value\n---\ntest: value\n", ""},
+		{"invalid yaml", YAMLValidator(), "test: value\n  other: value\n", "document 0: yaml: line 2: mapping values are not allowed"},
+		{"invalid second document", YAMLValidator(), "test: value\n---\n: value\n", "document 1: yaml: did not find expected key"},
+
+		{"kubernetes object", KubernetesObjectValidator(), testDeployment, ""},
+		{"multiple kubernetes objects", KubernetesObjectValidator(), testDeployment + "---\n" + testConfigMap, ""},
+		{"missing kind", KubernetesObjectValidator(), "apiVersion: v1\nmetadata:\n  name: test\n", "document 0: missing kind"},
+		{"missing name", KubernetesObjectValidator(), testConfigMap + "---\napiVersion: v1\nkind: ConfigMap\n", "document 1: missing metadata.name"},
+		{"missing all fields", KubernetesObjectValidator(), "test: value\n", "document 0: missing apiVersion\nmissing kind\nmissing metadata.name"},
+		{"not an object", KubernetesObjectValidator(), "- test\n", "document 0: error unmarshaling JSON"},
+
+		{"registered type", SchemeValidator(nil), testDeployment + "---\n" + testConfigMap, ""},
+		{"unknown field", SchemeValidator(nil), testConfigMap + "unknown: value\n", `document 0: strict decoding error: unknown field "unknown"`},
+		{"unregistered type", SchemeValidator(nil), "apiVersion: example.com/v1\nkind: Unknown\nmetadata:\n  name: test\n", `document 0: no kind "Unknown" is registered`},
+		{"custom scheme", SchemeValidator(runtime.NewScheme()), testConfigMap, `document 0: no kind "ConfigMap" is registered`},
+	}
+
+	for _, tt := range validatorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.v.Validate([]byte(tt.body))
+
+			if !test.MatchError(t, tt.wantErr, err) {
+				t.Fatalf("got %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}